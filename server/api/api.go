@@ -1,6 +1,7 @@
 package api
 
 import (
+	"ecom/server/auth"
 	"ecom/server/handlers"
 	"net/http"
 
@@ -23,9 +24,76 @@ func (app *App) Run(addr string) error {
 	m.Use(middleware.Logger)
 	m.Get("/", app.hs.HandleHome)
 	m.Route("/v1/products/", func(r chi.Router) {
+		r.Get("/suggest", app.hs.HandleSuggestProducts)
 		r.Get("/{id}", app.hs.HandleGetProduct)
 		r.Get("/", app.hs.HandleGetProducts)
 		r.Post("/{id}/rate", app.hs.HandleRateProduct)
+		r.Post("/{id}/click", app.hs.HandleRecordSearchClick)
+	})
+	m.Get("/v1/categories", app.hs.HandleListCategories)
+	m.Route("/v1/auth/", func(r chi.Router) {
+		r.Post("/signup", app.hs.HandleSignup)
+		r.Post("/login", app.hs.HandleLogin)
+		r.Post("/login/2fa", app.hs.HandleLoginMFA)
+		r.Post("/magic-link", app.hs.HandleSendMagicLink)
+		r.Post("/magic-link/verify", app.hs.HandleVerifyMagicLink)
+		r.Get("/oidc/{provider}/start", app.hs.HandleStartOIDC)
+		r.Get("/oidc/{provider}/callback", app.hs.HandleOIDCCallback)
+		r.Post("/refresh", app.hs.HandleRefresh)
+		r.Post("/logout", app.hs.HandleLogout)
+		r.Post("/me/email/confirm", app.hs.HandleConfirmEmailChange)
+		r.Post("/me/delete/confirm", app.hs.HandleConfirmAccountDeletion)
+		r.Post("/email/verify", app.hs.HandleVerifyEmail)
+		r.Post("/password/forgot", app.hs.HandleForgotPassword)
+		r.Post("/password/reset", app.hs.HandleResetPassword)
+		r.Group(func(r chi.Router) {
+			r.Use(app.hs.Authenticate)
+			r.Use(app.hs.RequireUserSession)
+			r.Get("/me", app.hs.HandleGetMe)
+			r.Put("/me", app.hs.HandleUpdateMe)
+			r.Get("/me/export", app.hs.HandleExportMe)
+			r.Post("/me/delete", app.hs.HandleRequestAccountDeletion)
+			r.Post("/email/verify/resend", app.hs.HandleResendVerification)
+			r.Post("/2fa/enroll", app.hs.HandleEnrollTOTP)
+			r.Post("/2fa/confirm", app.hs.HandleConfirmTOTP)
+			r.Post("/2fa/disable", app.hs.HandleDisableTOTP)
+			r.Post("/2fa/recovery-codes", app.hs.HandleRegenerateRecoveryCodes)
+			r.Get("/sessions", app.hs.HandleListMySessions)
+			r.Delete("/sessions", app.hs.HandleRevokeMySessions)
+			r.Delete("/sessions/{id}", app.hs.HandleRevokeMySession)
+			r.Get("/addresses", app.hs.HandleListAddresses)
+			r.Post("/addresses", app.hs.HandleCreateAddress)
+			r.Put("/addresses/{id}", app.hs.HandleUpdateAddress)
+			r.Delete("/addresses/{id}", app.hs.HandleDeleteAddress)
+		})
+	})
+	m.Route("/v1/admin/", func(r chi.Router) {
+		r.Use(app.hs.Authenticate)
+		r.Use(app.hs.RequireMFA)
+		r.With(app.hs.RequirePermission(auth.PermRolesWrite)).Put("/users/{id}/role", app.hs.HandleAssignRole)
+		r.With(app.hs.RequirePermission(auth.PermUsersWrite)).Post("/users/{id}/unlock", app.hs.HandleUnlockUser)
+		r.With(app.hs.RequirePermission(auth.PermUsersRead)).Get("/users/{id}/sessions", app.hs.HandleListUserSessions)
+		r.With(app.hs.RequirePermission(auth.PermUsersWrite)).Delete("/users/{id}/sessions", app.hs.HandleRevokeUserSessions)
+		r.With(app.hs.RequirePermission(auth.PermUsersWrite)).Delete("/users/{id}/sessions/{sid}", app.hs.HandleRevokeUserSession)
+		r.With(app.hs.RequirePermission(auth.PermRolesRead)).Get("/roles", app.hs.HandleListRoles)
+		r.With(app.hs.RequirePermission(auth.PermRolesWrite)).Post("/roles", app.hs.HandleCreateRole)
+		r.With(app.hs.RequirePermission(auth.PermRolesWrite)).Put("/roles/{name}/permissions", app.hs.HandleSetRolePermissions)
+		r.With(app.hs.RequirePermission(auth.PermRolesRead)).Get("/permissions", app.hs.HandleListPermissions)
+		r.With(app.hs.RequirePermission(auth.PermAPIKeysWrite)).Post("/service-accounts", app.hs.HandleCreateServiceAccount)
+		r.With(app.hs.RequirePermission(auth.PermAPIKeysRead)).Get("/api-keys", app.hs.HandleListAPIKeys)
+		r.With(app.hs.RequirePermission(auth.PermAPIKeysWrite)).Post("/api-keys", app.hs.HandleCreateAPIKey)
+		r.With(app.hs.RequirePermission(auth.PermAPIKeysWrite)).Delete("/api-keys/{id}", app.hs.HandleRevokeAPIKey)
+		r.With(app.hs.RequirePermission(auth.PermProductsWrite)).Post("/products", app.hs.HandleCreateProduct)
+		r.With(app.hs.RequirePermission(auth.PermProductsWrite)).Put("/products/{id}", app.hs.HandleUpdateProduct)
+		r.With(app.hs.RequirePermission(auth.PermProductsWrite)).Delete("/products/{id}", app.hs.HandleDeleteProduct)
+		r.With(app.hs.RequirePermission(auth.PermCategoriesWrite)).Post("/categories", app.hs.HandleCreateCategory)
+		r.With(app.hs.RequirePermission(auth.PermCategoriesWrite)).Put("/categories/{id}", app.hs.HandleUpdateCategory)
+		r.With(app.hs.RequirePermission(auth.PermCategoriesWrite)).Delete("/categories/{id}", app.hs.HandleDeleteCategory)
+		r.With(app.hs.RequirePermission(auth.PermSearchRead)).Get("/search/synonyms", app.hs.HandleListSynonyms)
+		r.With(app.hs.RequirePermission(auth.PermSearchWrite)).Post("/search/synonyms", app.hs.HandleCreateSynonyms)
+		r.With(app.hs.RequirePermission(auth.PermSearchWrite)).Put("/search/synonyms/{id}", app.hs.HandleUpdateSynonyms)
+		r.With(app.hs.RequirePermission(auth.PermSearchWrite)).Delete("/search/synonyms/{id}", app.hs.HandleDeleteSynonyms)
+		r.With(app.hs.RequirePermission(auth.PermSearchRead)).Get("/search/analytics", app.hs.HandleGetSearchAnalytics)
 	})
 	return http.ListenAndServe(addr, m)
 }
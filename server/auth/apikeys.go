@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"strings"
+)
+
+// apiKeyTag starts every API key, so leaked keys are easy to recognize.
+const apiKeyTag = "ek_"
+
+// NewAPIKey returns a key formatted as "ek_<prefix>_<secret>", the prefix to look it up
+// by and the hash of the secret to persist.
+func NewAPIKey() (key, prefix, hash string, err error) {
+	id, err := NewID()
+	if err != nil {
+		return "", "", "", err
+	}
+	prefix = id[:12]
+	secret, hash, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	return apiKeyTag + prefix + "_" + secret, prefix, hash, nil
+}
+
+// ParseAPIKey splits a key into its prefix and secret.
+func ParseAPIKey(key string) (prefix, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyTag)
+	if !ok {
+		return "", "", false
+	}
+	prefix, secret, ok = strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", "", false
+	}
+	return prefix, secret, true
+}
+
+// VerifyAPIKeySecret compares a secret against a stored hash in constant time.
+func VerifyAPIKeySecret(secret, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashToken(secret)), []byte(hash)) == 1
+}
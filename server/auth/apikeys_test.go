@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey(t *testing.T) {
+	key, prefix, hash, err := NewAPIKey()
+	require.NoError(t, err)
+
+	gotPrefix, secret, ok := ParseAPIKey(key)
+	require.True(t, ok)
+	assert.Equal(t, prefix, gotPrefix)
+	assert.True(t, VerifyAPIKeySecret(secret, hash))
+	assert.False(t, VerifyAPIKeySecret(secret+"x", hash))
+
+	for _, bad := range []string{"", "ek_", "ek_abc", "ek__secret", "xx_abc_secret"} {
+		_, _, ok := ParseAPIKey(bad)
+		assert.False(t, ok, bad)
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import "context"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID    int64
+	Role      string
+	SessionID string   // Refresh token family of the caller's session.
+	MFA       bool     // The caller's session was started with a second factor.
+	APIKeyID  int64    // Set if the caller authenticated with an API key.
+	Scopes    []string // Permissions an API key is limited to.
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the caller.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller stored by the authentication middleware, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
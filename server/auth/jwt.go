@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const issuer = "ecom"
+
+// Audiences keep the token kinds apart, so an MFA challenge token is never accepted as an access token.
+const (
+	audienceAccess = "access"
+	audienceMFA    = "mfa"
+)
+
+// mfaTTL bounds the time between the password step and the second factor step of a login.
+const mfaTTL = 5 * time.Minute
+
+// AccessClaims are the claims carried by an access token.
+type AccessClaims struct {
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"` // Refresh token family the token was issued from.
+	MFA       bool   `json:"mfa,omitempty"` // The session was started with a second factor.
+	jwt.RegisteredClaims
+}
+
+// UserID returns the user id stored in the subject claim.
+func (c AccessClaims) UserID() (int64, error) {
+	return strconv.ParseInt(c.Subject, 10, 64)
+}
+
+// JWTManager issues and verifies HS256-signed access tokens.
+type JWTManager struct {
+	secret    []byte
+	accessTTL time.Duration
+}
+
+func NewJWTManager(secret string, accessTTL time.Duration) *JWTManager {
+	return &JWTManager{secret: []byte(secret), accessTTL: accessTTL}
+}
+
+// IssueAccessToken signs a new access token for the user and returns it with its expiry time.
+func (m *JWTManager) IssueAccessToken(userID int64, role, sessionID string, mfa bool) (string, time.Time, error) {
+	return m.issue(userID, role, sessionID, mfa, audienceAccess, m.accessTTL)
+}
+
+// ParseAccessToken verifies the signature, issuer, audience and expiry of a token and returns its claims.
+func (m *JWTManager) ParseAccessToken(token string) (AccessClaims, error) {
+	return m.parse(token, audienceAccess)
+}
+
+// IssueMFAToken signs a short-lived token proving the user passed the password step of a login.
+func (m *JWTManager) IssueMFAToken(userID int64) (string, time.Time, error) {
+	return m.issue(userID, "", "", false, audienceMFA, mfaTTL)
+}
+
+// ParseMFAToken verifies a token issued by IssueMFAToken and returns its claims.
+func (m *JWTManager) ParseMFAToken(token string) (AccessClaims, error) {
+	return m.parse(token, audienceMFA)
+}
+
+func (m *JWTManager) issue(userID int64, role, sessionID string, mfa bool, audience string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	exp := now.Add(ttl)
+	claims := AccessClaims{
+		Role:      role,
+		SessionID: sessionID,
+		MFA:       mfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.FormatInt(userID, 10),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign %s token: %w", audience, err)
+	}
+	return token, exp, nil
+}
+
+func (m *JWTManager) parse(token, audience string) (AccessClaims, error) {
+	var claims AccessClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	if claims.Subject == "" {
+		return AccessClaims{}, errors.New("token has no subject")
+	}
+	return claims, nil
+}
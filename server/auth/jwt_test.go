@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTManager(t *testing.T) {
+	m := NewJWTManager("test-secret", 15*time.Minute)
+
+	t.Run("Round trip", func(t *testing.T) {
+		token, exp, err := m.IssueAccessToken(42, "admin", "family", true)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), exp, 5*time.Second)
+
+		claims, err := m.ParseAccessToken(token)
+		require.NoError(t, err)
+		userID, err := claims.UserID()
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), userID)
+		assert.Equal(t, "admin", claims.Role)
+		assert.Equal(t, "family", claims.SessionID)
+		assert.True(t, claims.MFA)
+	})
+
+	t.Run("MFA token is not an access token", func(t *testing.T) {
+		token, _, err := m.IssueMFAToken(42)
+		require.NoError(t, err)
+		_, err = m.ParseAccessToken(token)
+		assert.Error(t, err)
+
+		claims, err := m.ParseMFAToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "42", claims.Subject)
+	})
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		token, _, err := NewJWTManager("other-secret", time.Minute).IssueAccessToken(1, "user", "", false)
+		require.NoError(t, err)
+		_, err = m.ParseAccessToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		token, _, err := NewJWTManager("test-secret", -time.Minute).IssueAccessToken(1, "user", "", false)
+		require.NoError(t, err)
+		_, err = m.ParseAccessToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("Garbage", func(t *testing.T) {
+		_, err := m.ParseAccessToken("not.a.token")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Stored passwords are tagged with the algorithm that produced them, as "<algorithm>:<hash>".
+// Untagged values are legacy: raw bcrypt hashes written before tagging existed, or the
+// plaintext passwords of the seed data.
+const (
+	AlgBcrypt = "bcrypt"
+	AlgPlain  = "plain"
+)
+
+// PasswordHasher hashes new passwords with bcrypt at Cost and verifies every stored format.
+type PasswordHasher struct {
+	Cost int
+}
+
+// NewPasswordHasher returns a hasher for the bcrypt cost, or bcrypt.DefaultCost if cost is 0.
+func NewPasswordHasher(cost int) *PasswordHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &PasswordHasher{Cost: cost}
+}
+
+// Hash returns the tagged hash of a password.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return AlgBcrypt + ":" + string(hash), nil
+}
+
+// Verify checks a password against a stored value. needsRehash is true when the password
+// matched but the stored value should be replaced by a fresh Hash, because it uses another
+// algorithm, another cost, or isn't tagged.
+func (h *PasswordHasher) Verify(stored, password string) (ok, needsRehash bool) {
+	alg, hash, tagged := parseStored(stored)
+	switch alg {
+	case AlgBcrypt:
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false, false
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return true, !tagged || err != nil || cost != h.Cost
+	case AlgPlain:
+		if hash == "" {
+			return false, false
+		}
+		ok := subtle.ConstantTimeCompare([]byte(hash), []byte(password)) == 1
+		return ok, ok
+	default:
+		return false, false
+	}
+}
+
+// Upgrade converts a legacy stored value into a tagged one without knowing the password:
+// plaintext is hashed and raw bcrypt hashes are tagged. changed is false if stored is already tagged
+// or empty: accounts without a password, like service accounts, must not get the empty one.
+func (h *PasswordHasher) Upgrade(stored string) (upgraded string, changed bool, err error) {
+	alg, hash, tagged := parseStored(stored)
+	if tagged || stored == "" {
+		return stored, false, nil
+	}
+	if alg == AlgBcrypt {
+		return AlgBcrypt + ":" + hash, true, nil
+	}
+	upgraded, err = h.Hash(hash)
+	return upgraded, err == nil, err
+}
+
+// parseStored splits a stored value into its algorithm and hash.
+func parseStored(stored string) (alg, hash string, tagged bool) {
+	if alg, hash, ok := strings.Cut(stored, ":"); ok && (alg == AlgBcrypt || alg == AlgPlain) {
+		return alg, hash, true
+	}
+	if strings.HasPrefix(stored, "$2a$") || strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$") {
+		return AlgBcrypt, stored, false
+	}
+	return AlgPlain, stored, false
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	h := NewPasswordHasher(bcrypt.MinCost)
+
+	tagged, err := h.Hash("s3cret-pass")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(tagged, "bcrypt:"))
+
+	rawBcrypt, err := bcrypt.GenerateFromPassword([]byte("s3cret-pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+	otherCost, err := NewPasswordHasher(bcrypt.MinCost + 1).Hash("s3cret-pass")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		stored         string
+		password       string
+		expectedOK     bool
+		expectedRehash bool
+	}{
+		{name: "Tagged bcrypt", stored: tagged, password: "s3cret-pass", expectedOK: true},
+		{name: "Tagged bcrypt, wrong password", stored: tagged, password: "nope"},
+		{name: "Tagged bcrypt, other cost", stored: otherCost, password: "s3cret-pass", expectedOK: true, expectedRehash: true},
+		{name: "Untagged bcrypt", stored: string(rawBcrypt), password: "s3cret-pass", expectedOK: true, expectedRehash: true},
+		{name: "Plaintext seed password", stored: "admin1", password: "admin1", expectedOK: true, expectedRehash: true},
+		{name: "Plaintext, wrong password", stored: "admin1", password: "admin2"},
+		{name: "Empty stored value", stored: "", password: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, rehash := h.Verify(tc.stored, tc.password)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedRehash, rehash)
+		})
+	}
+
+	t.Run("Upgrade", func(t *testing.T) {
+		upgraded, changed, err := h.Upgrade("user7")
+		require.NoError(t, err)
+		assert.True(t, changed)
+		ok, rehash := h.Verify(upgraded, "user7")
+		assert.True(t, ok)
+		assert.False(t, rehash)
+
+		upgraded, changed, err = h.Upgrade(string(rawBcrypt))
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "bcrypt:"+string(rawBcrypt), upgraded)
+
+		_, changed, err = h.Upgrade(tagged)
+		require.NoError(t, err)
+		assert.False(t, changed)
+
+		upgraded, changed, err = h.Upgrade("")
+		require.NoError(t, err)
+		assert.False(t, changed, "accounts without a password keep having none")
+		ok, _ = h.Verify(upgraded, "")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,17 @@
+package auth
+
+// Permission names as stored in the permissions table.
+const (
+	PermUsersRead       = "users:read"
+	PermUsersWrite      = "users:write"
+	PermRolesRead       = "roles:read"
+	PermRolesWrite      = "roles:write"
+	PermProductsWrite   = "products:write"
+	PermCategoriesWrite = "categories:write"
+	PermOrdersRead      = "orders:read"
+	PermOrdersWrite     = "orders:write"
+	PermAPIKeysRead     = "api_keys:read"
+	PermAPIKeysWrite    = "api_keys:write"
+	PermSearchRead      = "search:read"
+	PermSearchWrite     = "search:write"
+)
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// SHA1 test vectors of RFC 6238 appendix B, truncated to 6 digits.
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1111111111, expected: "050471"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+		{unix: 20000000000, expected: "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, code, "time: %d", tc.unix)
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+	now := time.Now()
+
+	code, err := TOTPCode(secret, TOTPStep(now))
+	require.NoError(t, err)
+	step, ok := VerifyTOTP(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now), step)
+
+	_, ok = VerifyTOTP(secret, code, now.Add(30*time.Second))
+	assert.True(t, ok, "a code from the previous step is accepted")
+
+	_, ok = VerifyTOTP(secret, code, now.Add(2*time.Minute))
+	assert.False(t, ok, "an old code is rejected")
+
+	_, ok = VerifyTOTP(secret, "000000x", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Ecom Shop", "jane@example.com", "ABCDEF")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Ecom%20Shop:jane@example.com?"))
+	assert.Contains(t, uri, "secret=ABCDEF")
+	assert.Contains(t, uri, "issuer=Ecom+Shop")
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := NewRecoveryCode()
+	require.NoError(t, err)
+	assert.Len(t, code, 11)
+	assert.Equal(t, NormalizeRecoveryCode(code), NormalizeRecoveryCode(strings.ToUpper(code)))
+	assert.Len(t, NormalizeRecoveryCode(code), 10)
+}
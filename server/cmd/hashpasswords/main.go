@@ -0,0 +1,39 @@
+// Command hashpasswords tags every stored password with its hashing algorithm and
+// bcrypt-hashes the plaintext passwords of the seed data. It is safe to run repeatedly.
+package main
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/repos/users"
+	usersService "ecom/server/services/users"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Could not load .env file, will rely on environment variables.")
+	}
+
+	ctx := context.Background()
+	db, err := pgx.Connect(ctx, os.Getenv("DB_URL"))
+	if err != nil {
+		log.Fatal("failed to connect database", err)
+	}
+	defer db.Close(ctx)
+
+	bcryptCost, _ := strconv.Atoi(os.Getenv("BCRYPT_COST"))
+	svc := usersService.NewService(users.NewUserRepo(db), nil, auth.NewPasswordHasher(bcryptCost), nil, nil, usersService.Config{})
+
+	n, err := svc.MigratePasswords(ctx)
+	if err != nil {
+		log.Fatalf("migrated %d passwords before failing: %v", n, err)
+	}
+	fmt.Printf("migrated %d passwords\n", n)
+}
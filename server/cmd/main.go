@@ -3,13 +3,32 @@ package main
 import (
 	"context"
 	"ecom/server/api"
+	"ecom/server/auth"
 	"ecom/server/handlers"
+	"ecom/server/mailer"
+	"ecom/server/oidc"
 	"ecom/server/repos"
+	"ecom/server/repos/addresses"
+	"ecom/server/repos/apikeys"
+	"ecom/server/repos/categories"
+	"ecom/server/repos/permissions"
 	"ecom/server/repos/products"
+	"ecom/server/repos/search"
+	"ecom/server/repos/users"
+	addressesService "ecom/server/services/addresses"
+	apikeysService "ecom/server/services/apikeys"
+	"ecom/server/services/authz"
+	categoriesService "ecom/server/services/categories"
 	productsService "ecom/server/services/products"
+	searchService "ecom/server/services/search"
+	usersService "ecom/server/services/users"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/joho/godotenv"
@@ -25,12 +44,93 @@ func main() {
 	if err != nil {
 		log.Fatal("failed to connect database", err)
 	}
+	var searchRepo repos.ISearchRepo = search.NewSearchRepo(db)
+	var searchSvc *searchService.SearchService = searchService.NewService(searchRepo)
 	var productRepo repos.IProductRepo = products.NewProductRepo(db)
-	var productService *productsService.ProductService = productsService.NewService(productRepo)
 
-	handlers := handlers.NewHandlers(productService)
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		log.Fatal("JWT_SECRET is not set")
+	}
+	tokens := auth.NewJWTManager(jwtSecret, 15*time.Minute)
+	bcryptCost, _ := strconv.Atoi(os.Getenv("BCRYPT_COST")) // Zero falls back to bcrypt's default
+	passwords := auth.NewPasswordHasher(bcryptCost)
+	mail, err := newMailer()
+	if err != nil {
+		log.Fatal("failed to set up mailer: ", err)
+	}
+	var mfaRequiredRoles []string
+	if os.Getenv("REQUIRE_ADMIN_2FA") == "true" {
+		mfaRequiredRoles = []string{"admin"}
+	}
+	var userRepo repos.IUserRepo = users.NewUserRepo(db)
+	var userService *usersService.UserService = usersService.NewService(userRepo, tokens, passwords, mail, oidcProviders(), usersService.Config{
+		RefreshTTL:       7 * 24 * time.Hour,
+		AppURL:           envOr("APP_URL", "http://localhost:3000"),
+		TOTPIssuer:       envOr("TOTP_ISSUER", "Ecom"),
+		MFARequiredRoles: mfaRequiredRoles,
+	})
+
+	var permissionRepo repos.IPermissionRepo = permissions.NewPermissionRepo(db)
+	var policyService *authz.PolicyService = authz.NewService(permissionRepo)
+	var productService *productsService.ProductService = productsService.NewService(productRepo, searchRepo, policyService)
+
+	var apiKeyRepo repos.IAPIKeyRepo = apikeys.NewAPIKeyRepo(db)
+	var apiKeyService *apikeysService.APIKeyService = apikeysService.NewService(apiKeyRepo, userRepo, permissionRepo, policyService)
+
+	var addressRepo repos.IAddressRepo = addresses.NewAddressRepo(db)
+	var addressService *addressesService.AddressService = addressesService.NewService(addressRepo)
+
+	var categoryRepo repos.ICategoryRepo = categories.NewCategoryRepo(db)
+	var categoryService *categoriesService.CategoryService = categoriesService.NewService(categoryRepo)
+
+	handlers := handlers.NewHandlers(productService, userService, policyService, apiKeyService, addressService, categoryService, searchSvc)
 	app := api.NewApp(handlers)
 	fmt.Println("🤠 server running at: ", os.Getenv("SRV_ADDR"))
 	log.Fatal(app.Run(os.Getenv("SRV_ADDR")))
 
 }
+
+// newMailer picks the mailer from MAILER: "smtp" sends through SMTP_HOST, anything
+// else writes emails as files into MAIL_DIR for development.
+func newMailer() (mailer.Mailer, error) {
+	from := envOr("MAIL_FROM", "no-reply@ecom.local")
+	if os.Getenv("MAILER") == "smtp" {
+		port, err := strconv.Atoi(envOr("SMTP_PORT", "587"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+		return mailer.NewSMTPMailer(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASS"), from), nil
+	}
+	return mailer.NewFileMailer(envOr("MAIL_DIR", "./tmp/mail"), from)
+}
+
+// oidcProviders sets up the social login providers listed in OIDC_PROVIDERS, e.g. "google,github".
+// Each one NAME is configured by OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID and OIDC_<NAME>_CLIENT_SECRET.
+func oidcProviders() map[string]usersService.OIDCProvider {
+	apiURL := strings.TrimRight(envOr("API_URL", "http://localhost:8080"), "/")
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	providers := make(map[string]usersService.OIDCProvider)
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers[name] = oidc.NewClient(oidc.Config{
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  apiURL + "/v1/auth/oidc/" + name + "/callback",
+		}, httpClient)
+	}
+	return providers
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
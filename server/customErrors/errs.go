@@ -1,8 +1,33 @@
 package customErrors
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 var (
-	NotFound error = fmt.Errorf("not found error")
-	Internal       = fmt.Errorf("internal server error")
+	NotFound           error = fmt.Errorf("not found error")
+	Internal                 = fmt.Errorf("internal server error")
+	AlreadyExists            = fmt.Errorf("already exists")
+	InvalidCredentials       = fmt.Errorf("invalid email or password")
+	Unauthorized             = fmt.Errorf("unauthorized")
+	Forbidden                = fmt.Errorf("forbidden")
+	InvalidInput             = fmt.Errorf("invalid input")
+	Locked                   = fmt.Errorf("account temporarily locked")
+	TooManyRequests          = fmt.Errorf("too many requests")
+	Conflict                 = fmt.Errorf("conflict")
 )
+
+// RetryAfterError wraps an error the client can recover from by waiting.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", e.Err, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,57 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(30) UNIQUE NOT NULL,
+    description TEXT
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(60) UNIQUE NOT NULL,
+    description TEXT
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id BIGINT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+INSERT INTO roles (name, description) VALUES
+('admin', 'Full access to the store'),
+('support', 'Customer support agent'),
+('user', 'Regular customer')
+ON CONFLICT (name) DO NOTHING;
+
+-- Any other role already present on users becomes a role without permissions.
+INSERT INTO roles (name)
+SELECT DISTINCT role FROM users
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+('users:read', 'View user accounts'),
+('users:write', 'Edit user accounts'),
+('roles:read', 'View roles and permissions'),
+('roles:write', 'Create roles, grant permissions and assign roles'),
+('products:write', 'Create, edit and delete products'),
+('categories:write', 'Create, edit and delete categories'),
+('orders:read', 'View all orders'),
+('orders:write', 'Edit orders')
+ON CONFLICT (name) DO NOTHING;
+
+-- The admin role keeps every permission.
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name IN ('users:read', 'orders:read')
+WHERE r.name = 'support'
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS user_tokens;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS phone,
+    DROP COLUMN IF EXISTS name;
@@ -0,0 +1,18 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS name VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS phone VARCHAR(30);
+
+-- Single-use tokens mailed to users, e.g. to confirm an email change.
+-- payload holds purpose specific data, like the new email address.
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(30) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    payload TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS user_tokens_user_purpose_idx ON user_tokens (user_id, purpose);
@@ -0,0 +1,9 @@
+-- Failed login counters. scope is 'account' (key: lowercased email) or 'ip' (key: client IP).
+CREATE TABLE IF NOT EXISTS login_attempts (
+    scope VARCHAR(10) NOT NULL,
+    key VARCHAR(100) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, key)
+);
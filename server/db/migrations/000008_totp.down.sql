@@ -0,0 +1,6 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS mfa;
+DROP TABLE IF EXISTS totp_recovery_codes;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS totp_last_step,
+    DROP COLUMN IF EXISTS totp_enabled_at,
+    DROP COLUMN IF EXISTS totp_secret;
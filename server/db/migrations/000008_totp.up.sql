@@ -0,0 +1,19 @@
+-- totp_secret is set on enrollment, totp_enabled_at once the first code is confirmed.
+-- totp_last_step is the last accepted time step, so a code can't be replayed.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS totp_recovery_codes_user_idx ON totp_recovery_codes (user_id);
+
+-- Whether the session was started with a second factor, carried over on rotation.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- Client details shown in the session list. They are updated with every rotation.
+-- last_used_at is set when an access token of the session is used.
+ALTER TABLE refresh_tokens
+    ADD COLUMN IF NOT EXISTS user_agent VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS ip VARCHAR(45),
+    ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ;
@@ -0,0 +1,31 @@
+-- Service accounts are users without a password that only authenticate with API keys.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS service_account BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- prefix is the public part of a key, used to look it up; only the hash of the secret is stored.
+-- scopes limit the key to some of the permissions of its owner's role.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(60) NOT NULL,
+    prefix VARCHAR(16) UNIQUE NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_idx ON api_keys (user_id);
+
+INSERT INTO permissions (name, description) VALUES
+('api_keys:read', 'View API keys'),
+('api_keys:write', 'Create and revoke API keys and service accounts')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name IN ('api_keys:read', 'api_keys:write')
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
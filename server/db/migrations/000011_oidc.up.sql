@@ -0,0 +1,22 @@
+-- Accounts at OpenID Connect providers, linked to users. subject is the provider's stable user id.
+CREATE TABLE IF NOT EXISTS identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(30) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS identities_user_idx ON identities (user_id);
+
+-- Pending authorizations, looked up by the hash of the state parameter when the provider redirects back.
+CREATE TABLE IF NOT EXISTS oidc_states (
+    state_hash VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(30) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_address_idx;
+DROP INDEX IF EXISTS addresses_one_default_idx;
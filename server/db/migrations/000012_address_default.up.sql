@@ -0,0 +1,9 @@
+-- Keep the oldest default of users that somehow have several, then let the database
+-- enforce at most one default address per user.
+UPDATE addresses SET is_default = FALSE
+WHERE is_default AND id NOT IN (
+    SELECT MIN(id) FROM addresses WHERE is_default GROUP BY user_id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS addresses_one_default_idx ON addresses (user_id) WHERE is_default;
+CREATE INDEX IF NOT EXISTS orders_address_idx ON orders (address_id);
@@ -0,0 +1 @@
+ALTER TABLE addresses DROP CONSTRAINT IF EXISTS addresses_country_iso_check;
@@ -0,0 +1,7 @@
+-- Addresses store ISO 3166 alpha-2 country codes. Map the spellings found in existing
+-- data, then enforce the format for new rows without failing on rows we couldn't map.
+UPDATE addresses SET country = 'US' WHERE UPPER(TRIM(country)) IN ('USA', 'US', 'UNITED STATES', 'UNITED STATES OF AMERICA');
+UPDATE addresses SET state = UPPER(TRIM(state)) WHERE country = 'US' AND state IS NOT NULL;
+
+ALTER TABLE addresses
+    ADD CONSTRAINT addresses_country_iso_check CHECK (country ~ '^[A-Z]{2}$') NOT VALID;
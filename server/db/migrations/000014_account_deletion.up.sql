@@ -0,0 +1,9 @@
+-- Deleted accounts are anonymized rather than removed, so their orders stay on the books.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Orders are financial records: refuse to delete a user that still has some.
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_user_id_fkey;
+ALTER TABLE orders
+    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS orders_user_idx ON orders (user_id);
@@ -0,0 +1,6 @@
+-- Deleting a product must not take order history with it: products that were ordered can't be deleted.
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_product_id_fkey;
+ALTER TABLE order_items
+    ADD CONSTRAINT order_items_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS order_items_product_idx ON order_items (product_id);
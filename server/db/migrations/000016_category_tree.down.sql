@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS products_category_idx;
+DROP INDEX IF EXISTS categories_parent_idx;
+ALTER TABLE categories
+    DROP CONSTRAINT IF EXISTS categories_not_own_parent,
+    DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,7 @@
+-- Categories form a tree. Deleting a category moves its subcategories up to its parent.
+ALTER TABLE categories
+    ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES categories(id) ON DELETE SET NULL,
+    ADD CONSTRAINT categories_not_own_parent CHECK (parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS categories_parent_idx ON categories (parent_id);
+CREATE INDEX IF NOT EXISTS products_category_idx ON products (category_id);
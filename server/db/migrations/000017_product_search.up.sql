@@ -0,0 +1,8 @@
+-- Full-text search over products. Matches in the name weigh more than matches in the description.
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS products_search_idx ON products USING GIN (search_vector);
@@ -0,0 +1,2 @@
+DELETE FROM permissions WHERE name IN ('search:read', 'search:write');
+DROP TABLE IF EXISTS search_synonyms;
@@ -0,0 +1,22 @@
+-- Groups of equivalent search terms, e.g. {tv, television}. A search for one of them also
+-- finds products described with the others. Terms are lowercase and may be several words.
+CREATE TABLE IF NOT EXISTS search_synonyms (
+    id BIGSERIAL PRIMARY KEY,
+    terms TEXT[] NOT NULL CHECK (cardinality(terms) >= 2),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS search_synonyms_terms_idx ON search_synonyms USING GIN (terms);
+
+INSERT INTO permissions (name, description) VALUES
+('search:read', 'View search synonyms and search analytics'),
+('search:write', 'Manage search synonyms')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name IN ('search:read', 'search:write')
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
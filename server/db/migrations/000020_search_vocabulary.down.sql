@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS categories_search_vocabulary ON categories;
+DROP TRIGGER IF EXISTS products_search_vocabulary ON products;
+DROP FUNCTION IF EXISTS search_vocabulary_sync();
+DROP FUNCTION IF EXISTS search_vocabulary_words(TEXT);
+DROP TABLE IF EXISTS search_vocabulary;
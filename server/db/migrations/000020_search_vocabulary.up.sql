@@ -0,0 +1,60 @@
+-- The words of product names and descriptions and of category names, which misspelled
+-- searches are corrected to. uses counts the rows a word appears in; triggers keep it
+-- current so searches don't have to tokenize the catalog.
+CREATE TABLE IF NOT EXISTS search_vocabulary (
+    word TEXT PRIMARY KEY,
+    uses INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS search_vocabulary_word_trgm_idx ON search_vocabulary USING GIN (word gin_trgm_ops);
+
+CREATE OR REPLACE FUNCTION search_vocabulary_words(t TEXT) RETURNS SETOF TEXT AS $$
+    SELECT DISTINCT LOWER(w)
+    FROM regexp_split_to_table(COALESCE(t, ''), '[^[:alnum:]]+') AS w
+    WHERE LENGTH(w) > 1
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Works for products and categories alike: a row's text is its name and description, if any.
+CREATE OR REPLACE FUNCTION search_vocabulary_sync() RETURNS trigger AS $$
+DECLARE
+    old_text TEXT;
+    new_text TEXT;
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        old_text := (to_jsonb(OLD) ->> 'name') || ' ' || COALESCE(to_jsonb(OLD) ->> 'description', '');
+        UPDATE search_vocabulary v SET uses = v.uses - 1
+        FROM search_vocabulary_words(old_text) AS w(word)
+        WHERE v.word = w.word;
+        DELETE FROM search_vocabulary v
+        USING search_vocabulary_words(old_text) AS w(word)
+        WHERE v.word = w.word AND v.uses <= 0;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        new_text := (to_jsonb(NEW) ->> 'name') || ' ' || COALESCE(to_jsonb(NEW) ->> 'description', '');
+        INSERT INTO search_vocabulary (word, uses)
+        SELECT word, 1 FROM search_vocabulary_words(new_text) AS w(word)
+        ON CONFLICT (word) DO UPDATE SET uses = search_vocabulary.uses + 1;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS products_search_vocabulary ON products;
+CREATE TRIGGER products_search_vocabulary
+    AFTER INSERT OR DELETE OR UPDATE OF name, description ON products
+    FOR EACH ROW EXECUTE FUNCTION search_vocabulary_sync();
+
+DROP TRIGGER IF EXISTS categories_search_vocabulary ON categories;
+CREATE TRIGGER categories_search_vocabulary
+    AFTER INSERT OR DELETE OR UPDATE OF name ON categories
+    FOR EACH ROW EXECUTE FUNCTION search_vocabulary_sync();
+
+INSERT INTO search_vocabulary (word, uses)
+SELECT w.word, COUNT(*)
+FROM (
+    SELECT name || ' ' || COALESCE(description, '') AS t FROM products
+    UNION ALL
+    SELECT name FROM categories
+) AS catalog, search_vocabulary_words(catalog.t) AS w(word)
+GROUP BY w.word
+ON CONFLICT (word) DO UPDATE SET uses = EXCLUDED.uses;
@@ -0,0 +1,21 @@
+-- Every search of the product list, as typed but lowercased with its whitespace collapsed,
+-- with the number of products it found. Nothing identifies who searched; clicks name the search
+-- by a random token handed out with its results, so they can't be made up for other searches.
+CREATE TABLE IF NOT EXISTS search_queries (
+    id BIGSERIAL PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    query TEXT NOT NULL,
+    corrected_query TEXT,
+    result_count INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS search_queries_created_at_idx ON search_queries (created_at);
+
+-- Products opened from the results of a search, each counted once per search.
+CREATE TABLE IF NOT EXISTS search_clicks (
+    search_id BIGINT NOT NULL REFERENCES search_queries(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (search_id, product_id)
+);
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"ecom/server/handlers/validations"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v4"
+)
+
+func (h *Handlers) HandleListAddresses(w http.ResponseWriter, r *http.Request) {
+	as, err := h.AddressService.List(r.Context())
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve addresses")
+		return
+	}
+	writeJSON(w, http.StatusOK, as)
+}
+
+func (h *Handlers) HandleCreateAddress(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateAddress(r.Body)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	a, err := h.AddressService.Create(r.Context(), *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create address")
+		return
+	}
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (h *Handlers) HandleUpdateAddress(w http.ResponseWriter, r *http.Request) {
+	addressID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid address ID format")
+		return
+	}
+	req, err := validations.ParseAndValidateAddress(r.Body)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	a, err := h.AddressService.Update(r.Context(), addressID, *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to update address")
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (h *Handlers) HandleDeleteAddress(w http.ResponseWriter, r *http.Request) {
+	addressID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid address ID format")
+		return
+	}
+
+	if err := h.AddressService.Delete(r.Context(), addressID); err != nil {
+		writeServiceError(w, err, "Failed to delete address")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"ecom/server/handlers/validations"
+	"ecom/server/types"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v4"
+)
+
+func (h *Handlers) HandleCreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.CreateServiceAccountRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	u, err := h.APIKeyService.CreateServiceAccount(r.Context(), *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create service account")
+		return
+	}
+	writeJSON(w, http.StatusCreated, u)
+}
+
+func (h *Handlers) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	var userID int64
+	if val := r.URL.Query().Get("user_id"); val != "" {
+		id, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid 'user_id' value: must be an integer")
+			return
+		}
+		userID = id
+	}
+
+	keys, err := h.APIKeyService.List(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve api keys")
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *Handlers) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.CreateAPIKeyRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key, err := h.APIKeyService.Create(r.Context(), *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create api key")
+		return
+	}
+	writeJSON(w, http.StatusCreated, key)
+}
+
+func (h *Handlers) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid api key ID format")
+		return
+	}
+
+	if err := h.APIKeyService.Revoke(r.Context(), keyID); err != nil {
+		writeServiceError(w, err, "Failed to revoke api key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"ecom/server/handlers/validations"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v4"
+)
+
+func (h *Handlers) HandleListCategories(w http.ResponseWriter, r *http.Request) {
+	cs, err := h.CategoryService.Tree(r.Context())
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve categories")
+		return
+	}
+	writeJSON(w, http.StatusOK, cs)
+}
+
+func (h *Handlers) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateCategory(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c, err := h.CategoryService.Create(r.Context(), *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create category")
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (h *Handlers) HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid category ID format")
+		return
+	}
+	req, err := validations.ParseAndValidateCategory(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c, err := h.CategoryService.Update(r.Context(), categoryID, *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to update category")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// HandleDeleteCategory deletes a category and reports how many products lost it.
+// With ?dry_run=true it only reports the count.
+func (h *Handlers) HandleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid category ID format")
+		return
+	}
+	var dryRun bool
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid dry_run value")
+			return
+		}
+	}
+
+	res, err := h.CategoryService.Delete(r.Context(), categoryID, dryRun)
+	if err != nil {
+		writeServiceError(w, err, "Failed to delete category")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
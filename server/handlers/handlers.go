@@ -1,16 +1,28 @@
 package handlers
 
 import (
+	"ecom/server/services/addresses"
+	"ecom/server/services/apikeys"
+	"ecom/server/services/authz"
+	"ecom/server/services/categories"
 	"ecom/server/services/products"
+	"ecom/server/services/search"
+	"ecom/server/services/users"
 	"net/http"
 )
 
 type Handlers struct {
-	ProductService *products.ProductService
+	ProductService  *products.ProductService
+	UserService     *users.UserService
+	PolicyService   *authz.PolicyService
+	APIKeyService   *apikeys.APIKeyService
+	AddressService  *addresses.AddressService
+	CategoryService *categories.CategoryService
+	SearchService   *search.SearchService
 }
 
-func NewHandlers(productSvc *products.ProductService) *Handlers {
-	return &Handlers{ProductService: productSvc}
+func NewHandlers(productSvc *products.ProductService, userSvc *users.UserService, policySvc *authz.PolicyService, apiKeySvc *apikeys.APIKeyService, addressSvc *addresses.AddressService, categorySvc *categories.CategoryService, searchSvc *search.SearchService) *Handlers {
+	return &Handlers{ProductService: productSvc, UserService: userSvc, PolicyService: policySvc, APIKeyService: apiKeySvc, AddressService: addressSvc, CategoryService: categorySvc, SearchService: searchSvc}
 }
 
 func (h *Handlers) HandleHome(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecom/server/auth"
+	"ecom/server/repos"
+	repoAPIKeys "ecom/server/repos/apikeys"
+	apikeysSvc "ecom/server/services/apikeys"
+	"ecom/server/types"
+
+	"github.com/go-chi/chi/v4"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyRepo holds a single API key in memory.
+type keyRepo struct {
+	repos.IAPIKeyRepo
+	key repoAPIKeys.StoredKey
+}
+
+func (r *keyRepo) GetActiveByPrefix(_ context.Context, prefix string) (repoAPIKeys.StoredKey, error) {
+	if prefix != r.key.Prefix {
+		return repoAPIKeys.StoredKey{}, pgx.ErrNoRows
+	}
+	return r.key, nil
+}
+
+func (r *keyRepo) Touch(context.Context, int64) error { return nil }
+
+// TestAPIKeyOnSelfServiceE2E checks that a key can't manage the account of its owner.
+func TestAPIKeyOnSelfServiceE2E(t *testing.T) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	require.NoError(t, err)
+	repo := &keyRepo{key: repoAPIKeys.StoredKey{
+		APIKey: types.APIKey{ID: 1, UserID: 1, Prefix: prefix, Scopes: []string{auth.PermProductsWrite}},
+		Hash:   hash,
+		Role:   "admin",
+	}}
+	hs := NewHandlers(nil, nil, nil, apikeysSvc.NewService(repo, nil, nil, nil), nil, nil, nil)
+
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(hs.Authenticate)
+		r.Use(hs.RequireUserSession)
+		r.Put("/v1/auth/me", hs.HandleUpdateMe)
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/v1/auth/me", strings.NewReader(`{"email": "attacker@example.com"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "ApiKey "+key)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"ecom/server/services/users"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v4"
+)
+
+const oidcStateCookieName = "oidc_state"
+
+// setOIDCStateCookie ties a login to the browser that started it. Lax lets the cookie come
+// along on the provider's top-level redirect back to the callback.
+func setOIDCStateCookie(w http.ResponseWriter, state string, maxAge time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     "/v1/auth/oidc",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *Handlers) HandleStartOIDC(w http.ResponseWriter, r *http.Request) {
+	url, state, err := h.UserService.StartOIDC(r.Context(), chi.URLParam(r, "provider"))
+	if err != nil {
+		writeServiceError(w, err, "Failed to start login")
+		return
+	}
+	setOIDCStateCookie(w, state, users.OIDCStateTTL)
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// HandleOIDCCallback is where the provider redirects the browser back to.
+func (h *Handlers) HandleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		writeError(w, http.StatusUnauthorized, "login was denied by the provider: "+e)
+		return
+	}
+	code, state := q.Get("code"), q.Get("state")
+	if code == "" || state == "" {
+		writeError(w, http.StatusBadRequest, "missing 'code' or 'state'")
+		return
+	}
+
+	var browserState string
+	if c, err := r.Cookie(oidcStateCookieName); err == nil {
+		browserState = c.Value
+	}
+	setOIDCStateCookie(w, "", -time.Second) // A state is only good for one attempt.
+
+	res, err := h.UserService.FinishOIDC(r.Context(), chi.URLParam(r, "provider"), state, browserState, code, client(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to log in")
+		return
+	}
+	writeLoginResult(w, res)
+}
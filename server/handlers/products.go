@@ -50,6 +50,74 @@ func (h *Handlers) HandleGetProducts(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, products)
 }
+
+// HandleSuggestProducts answers the search box as the user types. Responses may be cached
+// briefly, as the same prefixes are typed over and over.
+func (h *Handlers) HandleSuggestProducts(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateSuggest(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.ProductService.Suggest(r.Context(), *req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to retrieve suggestions")
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handlers) HandleCreateProduct(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateProduct(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	product, err := h.ProductService.Create(r.Context(), *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create product")
+		return
+	}
+	writeJSON(w, http.StatusCreated, product)
+}
+
+func (h *Handlers) HandleUpdateProduct(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+	req, err := validations.ParseAndValidateProduct(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	product, err := h.ProductService.Update(r.Context(), productID, *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to update product")
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+func (h *Handlers) HandleDeleteProduct(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+
+	if err := h.ProductService.Delete(r.Context(), productID); err != nil {
+		writeServiceError(w, err, "Failed to delete product")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handlers) HandleRateProduct(w http.ResponseWriter, r *http.Request) {
 	writeError(w, http.StatusNotImplemented, "unimplemented")
 }
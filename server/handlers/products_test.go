@@ -9,10 +9,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
+	"ecom/server/auth"
 	repoProducts "ecom/server/repos/products"
+	repoSearch "ecom/server/repos/search"
 	productSvc "ecom/server/services/products"
+	searchSvc "ecom/server/services/search"
 	"ecom/server/types"
 
 	"github.com/go-chi/chi/v4"
@@ -42,12 +46,15 @@ func TestMain(m *testing.M) {
 	defer db.Close(context.Background())
 
 	repo := repoProducts.NewProductRepo(db)
-	service := productSvc.NewService(repo)
-	handler := NewHandlers(service)
+	searchRepo := repoSearch.NewSearchRepo(db)
+	service := productSvc.NewService(repo, searchRepo, nil)
+	handler := NewHandlers(service, nil, nil, nil, nil, nil, searchSvc.NewService(searchRepo))
 
 	router := chi.NewRouter()
+	router.Get("/products/suggest", handler.HandleSuggestProducts)
 	router.Get("/products/{id}", handler.HandleGetProduct)
 	router.Get("/products", handler.HandleGetProducts)
+	router.Post("/products/{id}/click", handler.HandleRecordSearchClick)
 
 	testServer = httptest.NewServer(router)
 	defer testServer.Close()
@@ -56,6 +63,46 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// TestSuggestProductsE2E tests the search-as-you-type endpoint.
+func TestSuggestProductsE2E(t *testing.T) {
+	t.Run("Success - Prefix of a word in the name", func(t *testing.T) {
+		resp, err := http.Get(testServer.URL + "/products/suggest?q=headph")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result types.Suggestions
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, result.Products)
+		assert.Equal(t, "SilentBeat Pro Headphones", result.Products[0].Name)
+		assert.LessOrEqual(t, len(result.Products), 8) // Default limit
+	})
+
+	t.Run("Success - Typo is forgiven", func(t *testing.T) {
+		resp, err := http.Get(testServer.URL + "/products/suggest?q=hedphones&limit=3")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var result types.Suggestions
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, result.Products)
+		assert.Contains(t, result.Products[0].Name, "Headphones")
+	})
+
+	t.Run("Failure - Too short", func(t *testing.T) {
+		resp, err := http.Get(testServer.URL + "/products/suggest?q=a")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
 // TestGetProductE2E tests the single product endpoint.
 func TestGetProductE2E(t *testing.T) {
 	t.Run("Success - Get existing product", func(t *testing.T) {
@@ -146,6 +193,83 @@ func TestGetProductsE2E(t *testing.T) {
 		assert.Equal(t, "QuantumLeap X1 Laptop", result.Products[0].Name)
 	})
 
+	t.Run("Success - Filtering by category", func(t *testing.T) {
+		resp, err := http.Get(testServer.URL + "/products?category=1&limit=100")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result repoProducts.GetAllResult
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, result.Products)
+		assert.Len(t, result.Products, result.TotalCount)
+		for _, p := range result.Products {
+			assert.Equal(t, int64(1), p.CategoryData.ID)
+		}
+	})
+
+	t.Run("Success - Search results sorted by relevance by default", func(t *testing.T) {
+		resp, err := http.Get(testServer.URL + "/products?search=headphone")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result repoProducts.GetAllResult
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, result.Products)
+		assert.Equal(t, "SilentBeat Pro Headphones", result.Products[0].Name)
+		assert.Greater(t, result.Products[0].Relevance, float32(0))
+		assert.NotEmpty(t, result.SearchToken, "The first page of a search is logged")
+	})
+
+	t.Run("Success - Facets only when asked for", func(t *testing.T) {
+		resp, err := http.Get(testServer.URL + "/products?category=1&facets=true")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result repoProducts.GetAllResult
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		require.NoError(t, err)
+
+		require.NotNil(t, result.Facets)
+		assert.Greater(t, len(result.Facets.Categories), 1, "The category facet ignores the category filter")
+		assert.NotEmpty(t, result.Facets.Prices)
+		assert.NotEmpty(t, result.Facets.Ratings)
+
+		resp, err = http.Get(testServer.URL + "/products?category=1")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		result = repoProducts.GetAllResult{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		require.NoError(t, err)
+		assert.Nil(t, result.Facets)
+	})
+
+	t.Run("Failure - Relevance without a search", func(t *testing.T) {
+		resp, err := http.Get(testServer.URL + "/products?sortBy=relevance")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Failure - Invalid category", func(t *testing.T) {
+		resp, err := http.Get(testServer.URL + "/products?category=1,abc")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
 	t.Run("Failure - Invalid query parameter value", func(t *testing.T) {
 		resp, err := http.Get(testServer.URL + "/products?priceMin=abc")
 		require.NoError(t, err)
@@ -169,3 +293,39 @@ func TestGetProductsE2E(t *testing.T) {
 		assert.Contains(t, string(body), "validation failed")
 	})
 }
+
+// TestRecordSearchClickE2E tests that clicks are only counted for the search that handed out the token.
+func TestRecordSearchClickE2E(t *testing.T) {
+	resp, err := http.Get(testServer.URL + "/products?search=headphone")
+	require.NoError(t, err)
+	var result repoProducts.GetAllResult
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Products)
+	require.NotEmpty(t, result.SearchToken)
+
+	click := func(productID int64, token string) int {
+		body := fmt.Sprintf(`{"search_token": %q}`, token)
+		resp, err := http.Post(fmt.Sprintf("%s/products/%d/click", testServer.URL, productID), "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	productID := result.Products[0].ID
+
+	assert.Equal(t, http.StatusNoContent, click(productID, result.SearchToken))
+	assert.Equal(t, http.StatusNoContent, click(productID, result.SearchToken), "A second click changes nothing")
+	assert.Equal(t, http.StatusUnprocessableEntity, click(productID, "made-up-token"))
+	assert.Equal(t, http.StatusUnprocessableEntity, click(999999, result.SearchToken), "Unknown product")
+
+	t.Run("Old searches no longer count clicks", func(t *testing.T) {
+		db, err := pgx.Connect(context.Background(), os.Getenv("DB_URL"))
+		require.NoError(t, err)
+		defer db.Close(context.Background())
+
+		_, err = db.Exec(context.Background(), `UPDATE search_queries SET created_at = NOW() - INTERVAL '2 hours' WHERE token_hash = $1`, auth.HashToken(result.SearchToken))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, click(productID, result.SearchToken))
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"ecom/server/handlers/validations"
+	"ecom/server/types"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v4"
+)
+
+func (h *Handlers) HandleListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.PolicyService.ListRoles(r.Context())
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve roles")
+		return
+	}
+	writeJSON(w, http.StatusOK, roles)
+}
+
+func (h *Handlers) HandleListPermissions(w http.ResponseWriter, r *http.Request) {
+	perms, err := h.PolicyService.ListPermissions(r.Context())
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve permissions")
+		return
+	}
+	writeJSON(w, http.StatusOK, perms)
+}
+
+func (h *Handlers) HandleCreateRole(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.CreateRoleRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := h.PolicyService.CreateRole(r.Context(), *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create role")
+		return
+	}
+	writeJSON(w, http.StatusCreated, role)
+}
+
+func (h *Handlers) HandleSetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.SetRolePermissionsRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.PolicyService.SetRolePermissions(r.Context(), chi.URLParam(r, "name"), req.Permissions); err != nil {
+		writeServiceError(w, err, "Failed to set role permissions")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleAssignRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+	req, err := validations.ParseAndValidateJSON[types.AssignRoleRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.PolicyService.AssignRole(r.Context(), userID, req.Role); err != nil {
+		writeServiceError(w, err, "Failed to assign role")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"ecom/server/handlers/validations"
+	"ecom/server/types"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v4"
+)
+
+func (h *Handlers) HandleListSynonyms(w http.ResponseWriter, r *http.Request) {
+	gs, err := h.SearchService.ListSynonyms(r.Context())
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve synonyms")
+		return
+	}
+	writeJSON(w, http.StatusOK, gs)
+}
+
+func (h *Handlers) HandleCreateSynonyms(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.SynonymGroupRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	g, err := h.SearchService.CreateSynonyms(r.Context(), *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to create synonyms")
+		return
+	}
+	writeJSON(w, http.StatusCreated, g)
+}
+
+func (h *Handlers) HandleUpdateSynonyms(w http.ResponseWriter, r *http.Request) {
+	groupID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid synonym group ID format")
+		return
+	}
+	req, err := validations.ParseAndValidateJSON[types.SynonymGroupRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	g, err := h.SearchService.UpdateSynonyms(r.Context(), groupID, *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to update synonyms")
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (h *Handlers) HandleDeleteSynonyms(w http.ResponseWriter, r *http.Request) {
+	groupID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid synonym group ID format")
+		return
+	}
+
+	if err := h.SearchService.DeleteSynonyms(r.Context(), groupID); err != nil {
+		writeServiceError(w, err, "Failed to delete synonyms")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRecordSearchClick is called when a product is opened from a page of search results,
+// with the SearchToken of the page.
+func (h *Handlers) HandleRecordSearchClick(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+	req, err := validations.ParseAndValidateJSON[types.SearchClickRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.SearchService.RecordClick(r.Context(), productID, *req); err != nil {
+		writeServiceError(w, err, "Failed to record click")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleGetSearchAnalytics(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateSearchAnalytics(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.SearchService.Analytics(r.Context(), *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve search analytics")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
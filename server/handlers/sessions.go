@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v4"
+)
+
+func (h *Handlers) HandleListMySessions(w http.ResponseWriter, r *http.Request) {
+	ss, err := h.UserService.MySessions(r.Context())
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve sessions")
+		return
+	}
+	writeJSON(w, http.StatusOK, ss)
+}
+
+func (h *Handlers) HandleRevokeMySession(w http.ResponseWriter, r *http.Request) {
+	if err := h.UserService.RevokeMySession(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeServiceError(w, err, "Failed to revoke session")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleRevokeMySessions(w http.ResponseWriter, r *http.Request) {
+	if err := h.UserService.RevokeMySessions(r.Context()); err != nil {
+		writeServiceError(w, err, "Failed to revoke sessions")
+		return
+	}
+	clearRefreshCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleListUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	ss, err := h.UserService.ListSessions(r.Context(), userID, "")
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve sessions")
+		return
+	}
+	writeJSON(w, http.StatusOK, ss)
+}
+
+func (h *Handlers) HandleRevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	if err := h.UserService.RevokeSession(r.Context(), userID, chi.URLParam(r, "sid")); err != nil {
+		writeServiceError(w, err, "Failed to revoke session")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleRevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	if err := h.UserService.RevokeAllSessions(r.Context(), userID); err != nil {
+		writeServiceError(w, err, "Failed to revoke sessions")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"ecom/server/handlers/validations"
+	"ecom/server/types"
+	"net/http"
+)
+
+func (h *Handlers) HandleLoginMFA(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.MFALoginRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.UserService.LoginMFA(r.Context(), *req, client(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to log in")
+		return
+	}
+	setRefreshCookie(w, res)
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handlers) HandleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	res, err := h.UserService.EnrollTOTP(r.Context())
+	if err != nil {
+		writeServiceError(w, err, "Failed to start two-factor enrollment")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handlers) HandleConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.TOTPCodeRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.UserService.ConfirmTOTP(r.Context(), req.Code)
+	if err != nil {
+		writeServiceError(w, err, "Failed to enable two-factor authentication")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handlers) HandleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.TOTPCodeRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.UserService.DisableTOTP(r.Context(), req.Code); err != nil {
+		writeServiceError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.TOTPCodeRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.UserService.RegenerateRecoveryCodes(r.Context(), req.Code)
+	if err != nil {
+		writeServiceError(w, err, "Failed to regenerate recovery codes")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"ecom/server/customErrors"
+	"ecom/server/handlers/validations"
+	"ecom/server/services/users"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v4"
+)
+
+const refreshCookieName = "refresh_token"
+
+// setRefreshCookie hands the refresh token to the browser. It is scoped to the auth routes
+// and unreadable from JS.
+func setRefreshCookie(w http.ResponseWriter, res users.AuthResult) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    res.RefreshToken,
+		Path:     "/v1/auth",
+		Expires:  res.RefreshExpiresAt,
+		MaxAge:   int(time.Until(res.RefreshExpiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// writeLoginResult answers a login: with the MFA challenge if a second step is needed,
+// else with the tokens.
+func writeLoginResult(w http.ResponseWriter, res users.AuthResult) {
+	if res.MFAChallenge != nil {
+		writeJSON(w, http.StatusOK, res.MFAChallenge)
+		return
+	}
+	setRefreshCookie(w, res)
+	writeJSON(w, http.StatusOK, res)
+}
+
+func clearRefreshCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    "",
+		Path:     "/v1/auth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (h *Handlers) HandleSignup(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.SignupRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.UserService.Signup(r.Context(), *req)
+	if err != nil {
+		if errors.Is(err, customErrors.AlreadyExists) {
+			writeError(w, http.StatusConflict, "email already registered")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to create user")
+		return
+	}
+	writeJSON(w, http.StatusCreated, user)
+}
+
+func (h *Handlers) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.LoginRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.UserService.Login(r.Context(), *req, client(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to log in")
+		return
+	}
+	writeLoginResult(w, res)
+}
+
+func (h *Handlers) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(refreshCookieName)
+	if err != nil || cookie.Value == "" {
+		writeError(w, http.StatusUnauthorized, "missing refresh token")
+		return
+	}
+
+	res, err := h.UserService.Refresh(r.Context(), cookie.Value, client(r))
+	if err != nil {
+		if errors.Is(err, customErrors.Unauthorized) {
+			clearRefreshCookie(w)
+			writeError(w, http.StatusUnauthorized, "invalid refresh token")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to refresh session")
+		return
+	}
+	setRefreshCookie(w, res)
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handlers) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(refreshCookieName); err == nil && cookie.Value != "" {
+		if err := h.UserService.Logout(r.Context(), cookie.Value); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to log out")
+			return
+		}
+	}
+	clearRefreshCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleGetMe(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.UserService.Me(r.Context())
+	if err != nil {
+		writeServiceError(w, err, "Failed to retrieve profile")
+		return
+	}
+	writeJSON(w, http.StatusOK, profile)
+}
+
+func (h *Handlers) HandleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.UpdateProfileRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	profile, err := h.UserService.UpdateMe(r.Context(), *req)
+	if err != nil {
+		writeServiceError(w, err, "Failed to update profile")
+		return
+	}
+	writeJSON(w, http.StatusOK, profile)
+}
+
+func (h *Handlers) HandleConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.TokenRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.UserService.ConfirmEmailChange(r.Context(), req.Token); err != nil {
+		writeServiceError(w, err, "Failed to confirm email change")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.TokenRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.UserService.VerifyEmail(r.Context(), req.Token); err != nil {
+		writeServiceError(w, err, "Failed to verify email")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	if err := h.UserService.ResendVerification(r.Context()); err != nil {
+		writeServiceError(w, err, "Failed to send verification email")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handlers) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.ForgotPasswordRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.UserService.ForgotPassword(r.Context(), req.Email); err != nil {
+		writeServiceError(w, err, "Failed to start password reset")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handlers) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.ResetPasswordRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.UserService.ResetPassword(r.Context(), *req); err != nil {
+		writeServiceError(w, err, "Failed to reset password")
+		return
+	}
+	clearRefreshCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleUnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	if err := h.UserService.UnlockAccount(r.Context(), userID); err != nil {
+		writeServiceError(w, err, "Failed to unlock account")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) HandleSendMagicLink(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.MagicLinkRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.UserService.SendMagicLink(r.Context(), req.Email, client(r)); err != nil {
+		writeServiceError(w, err, "Failed to send login link")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handlers) HandleVerifyMagicLink(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.TokenRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.UserService.LoginMagicLink(r.Context(), req.Token, client(r))
+	if err != nil {
+		writeServiceError(w, err, "Failed to log in")
+		return
+	}
+	writeLoginResult(w, res)
+}
+
+// HandleExportMe answers with everything stored about the caller, as a JSON file download.
+func (h *Handlers) HandleExportMe(w http.ResponseWriter, r *http.Request) {
+	ex, err := h.UserService.ExportMe(r.Context())
+	if err != nil {
+		writeServiceError(w, err, "Failed to export account data")
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%d-%s.json"`, ex.Profile.ID, ex.ExportedAt.Format("2006-01-02")))
+	writeJSON(w, http.StatusOK, ex)
+}
+
+func (h *Handlers) HandleRequestAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	if err := h.UserService.RequestAccountDeletion(r.Context()); err != nil {
+		writeServiceError(w, err, "Failed to request account deletion")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handlers) HandleConfirmAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	req, err := validations.ParseAndValidateJSON[types.TokenRequest](r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.UserService.ConfirmAccountDeletion(r.Context(), req.Token); err != nil {
+		writeServiceError(w, err, "Failed to delete account")
+		return
+	}
+	clearRefreshCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
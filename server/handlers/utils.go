@@ -1,14 +1,60 @@
 package handlers
 
 import (
+	"ecom/server/customErrors"
+	"ecom/server/handlers/validations"
+	"ecom/server/services/users"
 	"encoding/json"
+	"errors"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 )
 
 func writeError(w http.ResponseWriter, st int, msg string) {
 	http.Error(w, msg, st)
 }
 
+// writeServiceError maps the sentinel errors of the service layer to a status code.
+// Unknown errors are reported as a 500 with the fallback message, so internals don't leak.
+func writeServiceError(w http.ResponseWriter, err error, fallback string) {
+	var retryErr *customErrors.RetryAfterError
+	if errors.As(err, &retryErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+	}
+
+	switch {
+	case errors.Is(err, customErrors.NotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, customErrors.AlreadyExists), errors.Is(err, customErrors.Conflict):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, customErrors.InvalidInput):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, customErrors.InvalidCredentials), errors.Is(err, customErrors.Unauthorized):
+		writeError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, customErrors.Forbidden):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, customErrors.Locked):
+		writeError(w, http.StatusLocked, err.Error())
+	case errors.Is(err, customErrors.TooManyRequests):
+		writeError(w, http.StatusTooManyRequests, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// writeValidationError reports invalid input: field errors as a 422 with a JSON body
+// naming each field, anything else, like malformed JSON, as a 400.
+func writeValidationError(w http.ResponseWriter, err error) {
+	var fe validations.FieldErrors
+	if errors.As(err, &fe) {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]validations.FieldErrors{"errors": fe})
+		return
+	}
+	writeError(w, http.StatusBadRequest, err.Error())
+}
+
 func writeJSON(w http.ResponseWriter, st int, data any) {
 	bs, err := json.Marshal(data)
 	if err != nil {
@@ -19,3 +65,18 @@ func writeJSON(w http.ResponseWriter, st int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(bs)
 }
+
+// clientIP returns the IP of the peer. Forwarding headers are deliberately ignored since
+// clients can forge them; deploy behind a proxy that rewrites RemoteAddr if needed.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// client returns the details of the requesting client stored with a session.
+func client(r *http.Request) users.Client {
+	return users.Client{IP: clientIP(r), UserAgent: r.UserAgent()}
+}
@@ -0,0 +1,273 @@
+package validations
+
+import (
+	"ecom/server/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+// FieldErrors maps the JSON name of each invalid field to what is wrong with it.
+type FieldErrors map[string]string
+
+func (fe FieldErrors) Error() string {
+	msgs := make([]string, 0, len(fe))
+	for _, field := range slices.Sorted(maps.Keys(fe)) {
+		msgs = append(msgs, field+" "+fe[field])
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// countryRule describes the address conventions of a country. Postal codes are matched
+// after compacting: upper case, without spaces and hyphens.
+type countryRule struct {
+	postal         *regexp.Regexp
+	formatPostal   func(string) string // Turns a compacted code into its canonical form. Nil keeps it compact.
+	postalOptional bool
+	stateRequired  bool
+	states         map[string]string // Code to name. If set, state must be one of them.
+}
+
+// insertAt returns a formatter putting sep before position i of a compacted code,
+// counting from the end if i is negative.
+func insertAt(i int, sep string) func(string) string {
+	return func(s string) string {
+		n := i
+		if n < 0 {
+			n += len(s)
+		}
+		if n <= 0 || n >= len(s) {
+			return s
+		}
+		return s[:n] + sep + s[n:]
+	}
+}
+
+var (
+	fiveDigits = regexp.MustCompile(`^\d{5}$`)
+	fourDigits = regexp.MustCompile(`^\d{4}$`)
+)
+
+// countryRules holds the countries whose formats we know. Other valid ISO 3166 countries
+// are accepted with a free-form postal code and an optional state.
+var countryRules = map[string]countryRule{
+	"US": {
+		postal: regexp.MustCompile(`^\d{5}(\d{4})?$`), formatPostal: insertAt(5, "-"),
+		stateRequired: true, states: usStates,
+	},
+	"CA": {
+		postal: regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[A-Z]\d[A-Z]\d$`), formatPostal: insertAt(3, " "),
+		stateRequired: true, states: caProvinces,
+	},
+	"AU": {postal: fourDigits, stateRequired: true, states: auStates},
+	"BR": {
+		postal: regexp.MustCompile(`^\d{8}$`), formatPostal: insertAt(5, "-"),
+		stateRequired: true, states: brStates,
+	},
+	"MX": {postal: fiveDigits, stateRequired: true},
+	"IN": {postal: regexp.MustCompile(`^\d{6}$`), stateRequired: true},
+	"JP": {postal: regexp.MustCompile(`^\d{7}$`), formatPostal: insertAt(3, "-"), stateRequired: true},
+	"GB": {postal: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]?\d[A-Z]{2}$`), formatPostal: insertAt(-3, " ")},
+	"IE": {postal: regexp.MustCompile(`^[A-Z\d]{7}$`), formatPostal: insertAt(3, " "), postalOptional: true},
+	"NL": {postal: regexp.MustCompile(`^\d{4}[A-Z]{2}$`), formatPostal: insertAt(4, " ")},
+	"SE": {postal: fiveDigits, formatPostal: insertAt(3, " ")},
+	"PL": {postal: fiveDigits, formatPostal: insertAt(2, "-")},
+	"PT": {postal: regexp.MustCompile(`^\d{7}$`), formatPostal: insertAt(4, "-")},
+	"DE": {postal: fiveDigits},
+	"FR": {postal: fiveDigits},
+	"ES": {postal: fiveDigits},
+	"IT": {postal: fiveDigits},
+	"FI": {postal: fiveDigits},
+	"AT": {postal: fourDigits},
+	"BE": {postal: fourDigits},
+	"CH": {postal: fourDigits},
+	"DK": {postal: fourDigits},
+	"NO": {postal: fourDigits},
+}
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// ParseAndValidateAddress decodes an address, checks it against the rules of its country
+// and normalizes it in place: ISO 3166 alpha-2 country, canonical postal code and state code.
+// Validation problems are returned as FieldErrors.
+func ParseAndValidateAddress(body io.Reader) (*types.AddressRequest, error) {
+	req := new(types.AddressRequest)
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+
+	fe := FieldErrors{}
+	if err := validate.Struct(req); err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return nil, fmt.Errorf("validation failed: %w", err)
+		}
+		for _, verr := range verrs {
+			fe[jsonName(req, verr.StructField())] = describe(verr)
+		}
+	}
+	NormalizeAddress(req, fe)
+	if len(fe) > 0 {
+		return nil, fe
+	}
+	return req, nil
+}
+
+// NormalizeAddress rewrites an address into its canonical form, adding a message to fe
+// for every field that breaks the rules of the country. Fields already in fe are skipped.
+func NormalizeAddress(a *types.AddressRequest, fe FieldErrors) {
+	a.Line1 = spaces.ReplaceAllString(strings.TrimSpace(a.Line1), " ")
+	a.City = spaces.ReplaceAllString(strings.TrimSpace(a.City), " ")
+	a.State = spaces.ReplaceAllString(strings.TrimSpace(a.State), " ")
+	a.ZipCode = strings.ToUpper(spaces.ReplaceAllString(strings.TrimSpace(a.ZipCode), " "))
+
+	if _, ok := fe["country"]; ok {
+		return
+	}
+	country, ok := normalizeCountry(a.Country)
+	if !ok {
+		fe["country"] = "must be an ISO 3166 country code, like US or DEU"
+		return
+	}
+	a.Country = country
+
+	rule, known := countryRules[country]
+	if !known {
+		return
+	}
+
+	if _, ok := fe["zip_code"]; !ok {
+		compact := strings.NewReplacer(" ", "", "-", "").Replace(a.ZipCode)
+		switch {
+		case compact == "" && rule.postalOptional:
+			a.ZipCode = ""
+		case compact == "":
+			fe["zip_code"] = "is required in " + country
+		case !rule.postal.MatchString(compact):
+			fe["zip_code"] = "is not a valid postal code for " + country
+		case rule.formatPostal != nil:
+			a.ZipCode = rule.formatPostal(compact)
+		default:
+			a.ZipCode = compact
+		}
+	}
+
+	if _, ok := fe["state"]; !ok {
+		switch {
+		case a.State == "" && rule.stateRequired:
+			fe["state"] = "is required in " + country
+		case a.State != "" && rule.states != nil:
+			code, ok := lookupState(rule.states, a.State)
+			if !ok {
+				fe["state"] = "is not a state of " + country
+				return
+			}
+			a.State = code
+		}
+	}
+}
+
+// normalizeCountry accepts an alpha-2 or alpha-3 code in any case and returns its alpha-2 form.
+func normalizeCountry(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) != 2 && len(s) != 3 {
+		return "", false
+	}
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return "", false
+		}
+	}
+	r, err := language.ParseRegion(s)
+	if err != nil {
+		return "", false
+	}
+	r = r.Canonicalize()
+	if !r.IsCountry() {
+		return "", false
+	}
+	return r.String(), true
+}
+
+// lookupState matches a state code or name, ignoring case.
+func lookupState(states map[string]string, s string) (string, bool) {
+	if _, ok := states[strings.ToUpper(s)]; ok {
+		return strings.ToUpper(s), true
+	}
+	for code, name := range states {
+		if strings.EqualFold(name, s) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+func jsonName(v any, field string) string {
+	f, ok := reflect.TypeOf(v).Elem().FieldByName(field)
+	if !ok {
+		return field
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+func describe(verr validator.FieldError) string {
+	switch verr.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		return "must be at most " + verr.Param() + " characters"
+	default:
+		return "is invalid"
+	}
+}
+
+var usStates = map[string]string{
+	"AL": "Alabama", "AK": "Alaska", "AZ": "Arizona", "AR": "Arkansas", "CA": "California",
+	"CO": "Colorado", "CT": "Connecticut", "DE": "Delaware", "DC": "District of Columbia",
+	"FL": "Florida", "GA": "Georgia", "HI": "Hawaii", "ID": "Idaho", "IL": "Illinois",
+	"IN": "Indiana", "IA": "Iowa", "KS": "Kansas", "KY": "Kentucky", "LA": "Louisiana",
+	"ME": "Maine", "MD": "Maryland", "MA": "Massachusetts", "MI": "Michigan", "MN": "Minnesota",
+	"MS": "Mississippi", "MO": "Missouri", "MT": "Montana", "NE": "Nebraska", "NV": "Nevada",
+	"NH": "New Hampshire", "NJ": "New Jersey", "NM": "New Mexico", "NY": "New York",
+	"NC": "North Carolina", "ND": "North Dakota", "OH": "Ohio", "OK": "Oklahoma", "OR": "Oregon",
+	"PA": "Pennsylvania", "RI": "Rhode Island", "SC": "South Carolina", "SD": "South Dakota",
+	"TN": "Tennessee", "TX": "Texas", "UT": "Utah", "VT": "Vermont", "VA": "Virginia",
+	"WA": "Washington", "WV": "West Virginia", "WI": "Wisconsin", "WY": "Wyoming",
+	"AS": "American Samoa", "GU": "Guam", "MP": "Northern Mariana Islands", "PR": "Puerto Rico",
+	"VI": "U.S. Virgin Islands", "AA": "Armed Forces Americas", "AE": "Armed Forces Europe",
+	"AP": "Armed Forces Pacific",
+}
+
+var caProvinces = map[string]string{
+	"AB": "Alberta", "BC": "British Columbia", "MB": "Manitoba", "NB": "New Brunswick",
+	"NL": "Newfoundland and Labrador", "NS": "Nova Scotia", "NT": "Northwest Territories",
+	"NU": "Nunavut", "ON": "Ontario", "PE": "Prince Edward Island", "QC": "Quebec",
+	"SK": "Saskatchewan", "YT": "Yukon",
+}
+
+var auStates = map[string]string{
+	"ACT": "Australian Capital Territory", "NSW": "New South Wales", "NT": "Northern Territory",
+	"QLD": "Queensland", "SA": "South Australia", "TAS": "Tasmania", "VIC": "Victoria",
+	"WA": "Western Australia",
+}
+
+var brStates = map[string]string{
+	"AC": "Acre", "AL": "Alagoas", "AP": "Amapá", "AM": "Amazonas", "BA": "Bahia", "CE": "Ceará",
+	"DF": "Distrito Federal", "ES": "Espírito Santo", "GO": "Goiás", "MA": "Maranhão",
+	"MT": "Mato Grosso", "MS": "Mato Grosso do Sul", "MG": "Minas Gerais", "PA": "Pará",
+	"PB": "Paraíba", "PR": "Paraná", "PE": "Pernambuco", "PI": "Piauí", "RJ": "Rio de Janeiro",
+	"RN": "Rio Grande do Norte", "RS": "Rio Grande do Sul", "RO": "Rondônia", "RR": "Roraima",
+	"SC": "Santa Catarina", "SP": "São Paulo", "SE": "Sergipe", "TO": "Tocantins",
+}
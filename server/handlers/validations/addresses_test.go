@@ -0,0 +1,99 @@
+package validations
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndValidateAddress(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedErrors FieldErrors
+		country        string
+		state          string
+		zip            string
+	}{
+		{
+			name:    "US with alpha-3 country and state name",
+			body:    `{"line1": " 123  Main St ", "city": "Anytown", "state": "california", "zip_code": "12345 6789", "country": "usa"}`,
+			country: "US", state: "CA", zip: "12345-6789",
+		},
+		{
+			name:    "Canadian postal code gets its space",
+			body:    `{"line1": "1 Rue", "city": "Montréal", "state": "qc", "zip_code": "h2x1y4", "country": "CA"}`,
+			country: "CA", state: "QC", zip: "H2X 1Y4",
+		},
+		{
+			name:    "UK is an alias of GB",
+			body:    `{"line1": "10 Downing St", "city": "London", "zip_code": "sw1a2aa", "country": "UK"}`,
+			country: "GB", zip: "SW1A 2AA",
+		},
+		{
+			name:    "Country without rules keeps a free-form postal code",
+			body:    `{"line1": "1 Road", "city": "Nairobi", "zip_code": " 00100 ", "country": "KEN"}`,
+			country: "KE", zip: "00100",
+		},
+		{
+			name:    "Irish Eircode is optional",
+			body:    `{"line1": "1 Street", "city": "Dublin", "country": "IE"}`,
+			country: "IE",
+		},
+		{
+			name: "US errors are reported per field",
+			body: `{"line1": "1 Main St", "city": "Anytown", "state": "Narnia", "zip_code": "1234", "country": "US"}`,
+			expectedErrors: FieldErrors{
+				"state":    "is not a state of US",
+				"zip_code": "is not a valid postal code for US",
+			},
+		},
+		{
+			name: "Missing state and postal code",
+			body: `{"line1": "1 Main St", "city": "Anytown", "country": "US"}`,
+			expectedErrors: FieldErrors{
+				"state":    "is required in US",
+				"zip_code": "is required in US",
+			},
+		},
+		{
+			name: "Unknown country and struct errors",
+			body: `{"line1": "", "city": "Anytown", "country": "Atlantis"}`,
+			expectedErrors: FieldErrors{
+				"line1":   "is required",
+				"country": "must be an ISO 3166 country code, like US or DEU",
+			},
+		},
+		{
+			name:           "Region that isn't a country",
+			body:           `{"line1": "1 Main St", "city": "Brussels", "country": "EU"}`,
+			expectedErrors: FieldErrors{"country": "must be an ISO 3166 country code, like US or DEU"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := ParseAndValidateAddress(strings.NewReader(tc.body))
+			if tc.expectedErrors != nil {
+				var fe FieldErrors
+				require.ErrorAs(t, err, &fe)
+				assert.Equal(t, tc.expectedErrors, fe)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.country, req.Country)
+			assert.Equal(t, tc.state, req.State)
+			assert.Equal(t, tc.zip, req.ZipCode)
+		})
+	}
+
+	t.Run("Malformed JSON is not a field error", func(t *testing.T) {
+		_, err := ParseAndValidateAddress(strings.NewReader(`{"line1": 1}`))
+		var fe FieldErrors
+		assert.Error(t, err)
+		assert.False(t, errors.As(err, &fe))
+	})
+}
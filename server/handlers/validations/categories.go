@@ -0,0 +1,26 @@
+package validations
+
+import (
+	"ecom/server/types"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseAndValidateCategory decodes and validates the JSON body of a category write. Like product
+// names, the name is trimmed first, so a name of only spaces is rejected as missing.
+func ParseAndValidateCategory(body io.Reader) (*types.CategoryRequest, error) {
+	req := &types.CategoryRequest{}
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	if err := validate.Struct(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	return req, nil
+}
@@ -0,0 +1,24 @@
+package validations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndValidateCategory(t *testing.T) {
+	req, err := ParseAndValidateCategory(strings.NewReader(`{"name": "  Lamps ", "parent_id": 2}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Lamps", req.Name)
+
+	_, err = ParseAndValidateCategory(strings.NewReader(`{"name": "   "}`))
+	assert.ErrorContains(t, err, "Name", "a name of only spaces is missing")
+
+	_, err = ParseAndValidateCategory(strings.NewReader(`{"name": " ` + strings.Repeat("a", 60) + ` "}`))
+	assert.NoError(t, err, "the length is checked on the trimmed name")
+
+	_, err = ParseAndValidateCategory(strings.NewReader(`{"name": "Lamps", "parent_id": 0}`))
+	assert.ErrorContains(t, err, "ParentID")
+}
@@ -2,7 +2,10 @@ package validations
 
 import (
 	"ecom/server/types"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 	"strconv"
 	"strings"
@@ -44,6 +47,17 @@ func ParseAndValidateGetProducts(q url.Values) (*types.GetProductsRequest, error
 		req.MinScore = &i
 	}
 
+	if val := q.Get("category"); val != "" {
+		// One or several comma-separated ids, e.g. ?category=1,3
+		for _, s := range strings.Split(val, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid 'category' value: must be comma-separated integers")
+			}
+			req.CategoryIDs = append(req.CategoryIDs, id)
+		}
+	}
+
 	if val := q.Get("limit"); val != "" {
 		i, err := strconv.Atoi(val)
 		if err != nil {
@@ -53,8 +67,28 @@ func ParseAndValidateGetProducts(q url.Values) (*types.GetProductsRequest, error
 	}
 
 	if q.Has("cursor") {
-		// Assumes cursor is sent as a comma-separated string, e.g., ?cursor=value1,value2
+		// The cursor is the NextCursor of the previous page: ?cursor=value1,value2 and, when a
+		// corrected search was run, its base64url encoding as a third value.
 		req.Cursor = strings.Split(q.Get("cursor"), ",")
+		if len(req.Cursor) == 3 {
+			corrected, err := base64.RawURLEncoding.DecodeString(req.Cursor[2])
+			if err != nil || req.SearchString == nil {
+				return nil, fmt.Errorf("invalid 'cursor' value")
+			}
+			req.Cursor[2] = string(corrected)
+		}
+	}
+
+	if val := q.Get("facets"); val != "" {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'facets' value: must be true or false")
+		}
+		req.Facets = b
+	}
+
+	if req.SortBy == "relevance" && req.SearchString == nil {
+		return nil, fmt.Errorf("invalid 'sortBy' value: relevance requires 'search'")
 	}
 
 	if err := validate.Struct(req); err != nil {
@@ -64,3 +98,64 @@ func ParseAndValidateGetProducts(q url.Values) (*types.GetProductsRequest, error
 
 	return req, nil
 }
+
+// ParseAndValidateSuggest pulls and validates query params for the search suggestions.
+func ParseAndValidateSuggest(q url.Values) (*types.SuggestRequest, error) {
+	req := &types.SuggestRequest{Query: strings.TrimSpace(q.Get("q"))}
+
+	if val := q.Get("limit"); val != "" {
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'limit' value: must be an integer")
+		}
+		req.Limit = i
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	return req, nil
+}
+
+// ParseAndValidateSearchAnalytics pulls and validates query params for the search reports.
+func ParseAndValidateSearchAnalytics(q url.Values) (*types.SearchAnalyticsRequest, error) {
+	req := &types.SearchAnalyticsRequest{}
+
+	if val := q.Get("days"); val != "" {
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'days' value: must be an integer")
+		}
+		req.Days = i
+	}
+
+	if val := q.Get("limit"); val != "" {
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'limit' value: must be an integer")
+		}
+		req.Limit = i
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	return req, nil
+}
+
+// ParseAndValidateProduct decodes and validates the JSON body of a product write. The name is
+// trimmed first, as it is stored that way, so a name of only spaces is rejected as missing.
+func ParseAndValidateProduct(body io.Reader) (*types.ProductRequest, error) {
+	req := &types.ProductRequest{}
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	if err := validate.Struct(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	return req, nil
+}
@@ -0,0 +1,37 @@
+package validations
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndValidateProduct(t *testing.T) {
+	req, err := ParseAndValidateProduct(strings.NewReader(`{"name": "  Desk Lamp ", "price": 19.99}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Desk Lamp", req.Name)
+
+	_, err = ParseAndValidateProduct(strings.NewReader(`{"name": "   ", "price": 19.99}`))
+	assert.ErrorContains(t, err, "Name", "a name of only spaces is missing")
+
+	_, err = ParseAndValidateProduct(strings.NewReader(`{"name": "Lamp", "price": 19.99, "stock": 3}`))
+	assert.ErrorContains(t, err, "invalid request body")
+}
+
+func TestParseAndValidateGetProductsCursor(t *testing.T) {
+	req, err := ParseAndValidateGetProducts(url.Values{"search": {"hedphones"}, "cursor": {"0.5,12,aGVhZHBob25lcw"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.5", "12", "headphones"}, req.Cursor, "the corrected search is decoded")
+
+	_, err = ParseAndValidateGetProducts(url.Values{"cursor": {"0.5,12,aGVhZHBob25lcw"}})
+	assert.ErrorContains(t, err, "cursor", "a corrected search needs a search")
+
+	_, err = ParseAndValidateGetProducts(url.Values{"search": {"hedphones"}, "cursor": {"0.5,12,not base64"}})
+	assert.ErrorContains(t, err, "cursor")
+
+	_, err = ParseAndValidateGetProducts(url.Values{"cursor": {"12"}})
+	assert.ErrorContains(t, err, "Cursor")
+}
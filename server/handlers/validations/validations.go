@@ -1,5 +1,25 @@
 package validations
 
-import "github.com/go-playground/validator/v10"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/go-playground/validator/v10"
+)
 
 var validate = validator.New()
+
+// ParseAndValidateJSON decodes a JSON request body into a T, rejecting unknown fields, and validates it.
+func ParseAndValidateJSON[T any](body io.Reader) (*T, error) {
+	req := new(T)
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	if err := validate.Struct(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	return req, nil
+}
@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes every email as a .eml file into Dir instead of sending it.
+// It is meant for development and tests.
+type FileMailer struct {
+	Dir  string
+	From string
+	seq  atomic.Int64
+}
+
+// NewFileMailer returns a mailer writing into dir, creating it if needed.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{Dir: dir, From: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// The sequence number keeps names unique and sortable within the same nanosecond.
+	name := fmt.Sprintf("%d-%04d-%s.eml", time.Now().UnixNano(), m.seq.Add(1), sanitize(msg.To))
+	if err := os.WriteFile(filepath.Join(m.Dir, name), render(m.From, msg), 0o644); err != nil {
+		return fmt.Errorf("failed to write email to %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// sanitize keeps an address usable as part of a file name.
+func sanitize(addr string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		}
+		return '_'
+	}, addr)
+}
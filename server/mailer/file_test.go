@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "shop@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hello", Body: "first"}))
+	require.NoError(t, m.Send(context.Background(), Message{To: "../evil/../x@example.com", Subject: "Hi", Body: "second"}))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "both emails must land directly in the directory")
+
+	first, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(first), "From: shop@example.com\r\n")
+	assert.Contains(t, string(first), "To: jane@example.com\r\n")
+	assert.Contains(t, string(first), "Subject: Hello\r\n")
+	assert.Contains(t, string(first), "\r\n\r\nfirst")
+
+	t.Run("Canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Error(t, m.Send(ctx, Message{To: "jane@example.com"}))
+	})
+}
@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTPMailer sends emails through an SMTP server, using STARTTLS when the server offers it.
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth // Nil for servers without authentication
+}
+
+// NewSMTPMailer returns a mailer for host:port. Username may be empty to skip authentication.
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{Addr: net.JoinHostPort(host, strconv.Itoa(port)), From: from}
+	if username != "" {
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.Addr, m.Auth, m.From, []string{msg.To}, render(m.From, msg)); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+// Package oidc is a minimal OpenID Connect relying party: the authorization code flow
+// with PKCE, and verification of RS256 signed ID tokens against the provider's JWKS.
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config describes a provider and our registration with it.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string // Defaults to openid, email and profile.
+}
+
+// Claims are the verified facts about the user in an ID token.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type idTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Client talks to one provider. Its discovery document and signing keys are
+// fetched on first use and cached.
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	mu   sync.Mutex
+	meta *discovery
+	keys map[string]*rsa.PublicKey
+}
+
+func NewClient(cfg Config, httpClient *http.Client) *Client {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Client{cfg: cfg, http: httpClient}
+}
+
+// AuthCodeURL returns the URL to send the browser to. codeChallenge is the S256 challenge
+// of a verifier from NewPKCE.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", c.cfg.ClientID)
+	q.Set("redirect_uri", c.cfg.RedirectURL)
+	q.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the claims of the verified ID token.
+// nonce must be the one passed to AuthCodeURL.
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier, nonce string) (Claims, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("client_secret", c.cfg.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var res struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := c.doJSON(req, &res)
+	if err != nil {
+		return Claims{}, fmt.Errorf("token request failed: %w", err)
+	}
+	if status != http.StatusOK || res.Error != "" {
+		return Claims{}, fmt.Errorf("token request failed with status %d: %s %s", status, res.Error, res.ErrorDescription)
+	}
+	if res.IDToken == "" {
+		return Claims{}, errors.New("token response has no id_token")
+	}
+	return c.verifyIDToken(ctx, res.IDToken, nonce)
+}
+
+func (c *Client) verifyIDToken(ctx context.Context, token, nonce string) (Claims, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(c.cfg.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("invalid id token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return Claims{}, errors.New("invalid id token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return Claims{}, errors.New("invalid id token: no subject")
+	}
+	return Claims{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+func (c *Client) discover(ctx context.Context) (discovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.meta != nil {
+		return *c.meta, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(c.cfg.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return discovery{}, err
+	}
+	var meta discovery
+	status, err := c.doJSON(req, &meta)
+	if err != nil {
+		return discovery{}, fmt.Errorf("failed to fetch discovery document of %s: %w", c.cfg.Issuer, err)
+	}
+	if status != http.StatusOK {
+		return discovery{}, fmt.Errorf("failed to fetch discovery document of %s: status %d", c.cfg.Issuer, status)
+	}
+	// The issuer must match exactly, or ID tokens of another provider could be accepted.
+	if meta.Issuer != c.cfg.Issuer {
+		return discovery{}, fmt.Errorf("discovery document issuer %q doesn't match %q", meta.Issuer, c.cfg.Issuer)
+	}
+	c.meta = &meta
+	return meta, nil
+}
+
+// key returns the signing key with the id, fetching the key set again if it is unknown,
+// so rotated keys are picked up.
+func (c *Client) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k, ok := c.keys[kid]; ok {
+		return k, nil
+	}
+	keys, err := c.fetchKeys(ctx, meta.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	c.keys = keys
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (c *Client) fetchKeys(ctx context.Context, jwksURI string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	status, err := c.doJSON(req, &set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch signing keys: status %d", status)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
+
+// doJSON sends req and decodes the response body, whatever its status, into v.
+func (c *Client) doJSON(req *http.Request, v any) (int, error) {
+	res, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return res.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return res.StatusCode, fmt.Errorf("invalid json response: %w", err)
+	}
+	return res.StatusCode, nil
+}
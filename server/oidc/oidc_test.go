@@ -0,0 +1,112 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"ecom/server/oidc"
+	"ecom/server/oidc/oidctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://app.test/v1/auth/oidc/test/callback"
+
+// authorize plays the browser: it follows the authorization URL and returns the
+// code and state the provider redirects back with.
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+
+	loc, err := url.Parse(res.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, redirectURL, loc.Scheme+"://"+loc.Host+loc.Path)
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	user := oidctest.User{Subject: "1234", Email: "jane@example.com", EmailVerified: true, Name: "Jane"}
+	idp := oidctest.NewServer("client", "secret", user)
+	defer idp.Close()
+
+	newClient := func(secret string) *oidc.Client {
+		return oidc.NewClient(oidc.Config{
+			Issuer:       idp.URL,
+			ClientID:     "client",
+			ClientSecret: secret,
+			RedirectURL:  redirectURL,
+		}, idp.Client())
+	}
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		c := newClient("secret")
+		verifier, challenge, err := oidc.NewPKCE()
+		require.NoError(t, err)
+		authURL, err := c.AuthCodeURL(ctx, "state-1", "nonce-1", challenge)
+		require.NoError(t, err)
+
+		code, state := authorize(t, authURL)
+		assert.Equal(t, "state-1", state)
+
+		claims, err := c.Exchange(ctx, code, verifier, "nonce-1")
+		require.NoError(t, err)
+		assert.Equal(t, oidc.Claims{Subject: "1234", Email: "jane@example.com", EmailVerified: true, Name: "Jane"}, claims)
+
+		_, err = c.Exchange(ctx, code, verifier, "nonce-1")
+		assert.Error(t, err, "a code can only be redeemed once")
+	})
+
+	t.Run("Wrong verifier", func(t *testing.T) {
+		c := newClient("secret")
+		_, challenge, err := oidc.NewPKCE()
+		require.NoError(t, err)
+		otherVerifier, _, err := oidc.NewPKCE()
+		require.NoError(t, err)
+		authURL, err := c.AuthCodeURL(ctx, "state", "nonce", challenge)
+		require.NoError(t, err)
+
+		code, _ := authorize(t, authURL)
+		_, err = c.Exchange(ctx, code, otherVerifier, "nonce")
+		assert.Error(t, err)
+	})
+
+	t.Run("Wrong nonce", func(t *testing.T) {
+		c := newClient("secret")
+		verifier, challenge, err := oidc.NewPKCE()
+		require.NoError(t, err)
+		authURL, err := c.AuthCodeURL(ctx, "state", "nonce", challenge)
+		require.NoError(t, err)
+
+		code, _ := authorize(t, authURL)
+		_, err = c.Exchange(ctx, code, verifier, "other-nonce")
+		assert.ErrorContains(t, err, "nonce")
+	})
+
+	t.Run("Wrong client secret", func(t *testing.T) {
+		c := newClient("wrong")
+		verifier, challenge, err := oidc.NewPKCE()
+		require.NoError(t, err)
+		authURL, err := c.AuthCodeURL(ctx, "state", "nonce", challenge)
+		require.NoError(t, err)
+
+		code, _ := authorize(t, authURL)
+		_, err = c.Exchange(ctx, code, verifier, "nonce")
+		assert.ErrorContains(t, err, "invalid_client")
+	})
+
+	t.Run("Issuer mismatch", func(t *testing.T) {
+		c := oidc.NewClient(oidc.Config{Issuer: idp.URL + "/", ClientID: "client", RedirectURL: redirectURL}, idp.Client())
+		_, err := c.AuthCodeURL(ctx, "state", "nonce", "challenge")
+		assert.ErrorContains(t, err, "issuer")
+	})
+}
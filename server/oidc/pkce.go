@@ -0,0 +1,24 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// NewPKCE returns a random code verifier and its S256 code challenge (RFC 7636).
+func NewPKCE() (verifier, challenge string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(b)
+	return verifier, S256Challenge(verifier), nil
+}
+
+// S256Challenge derives the code challenge of a verifier.
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,159 @@
+package addresses
+
+import (
+	"context"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInUse is returned when deleting an address an order still has to be shipped to.
+var ErrInUse = errors.New("address is used by an order in progress")
+
+// ClosedOrderStatuses are the statuses of orders that no longer need their address.
+// Orders in any other status, including ones added later such as "processing", are open.
+var ClosedOrderStatuses = []string{"completed", "cancelled"}
+
+type AddressRepo struct {
+	DB *pgx.Conn
+}
+
+func NewAddressRepo(db *pgx.Conn) *AddressRepo {
+	return &AddressRepo{DB: db}
+}
+
+const addressColumns = `id, line1, city, COALESCE(state, ''), COALESCE(zip_code, ''), country, is_default`
+
+func scanAddress(row pgx.Row) (types.Address, error) {
+	var a types.Address
+	err := row.Scan(&a.ID, &a.Line1, &a.City, &a.State, &a.ZipCode, &a.Country, &a.IsDefault)
+	return a, err
+}
+
+// List returns the addresses of a user, the default one first.
+func (repo *AddressRepo) List(ctx context.Context, userID int64) ([]types.Address, error) {
+	sql := `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1 ORDER BY is_default DESC, id`
+	rows, err := repo.DB.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query addresses: %w", err)
+	}
+	defer rows.Close()
+
+	as := []types.Address{}
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan address row: %w", err)
+		}
+		as = append(as, a)
+	}
+	return as, rows.Err()
+}
+
+// withUserLock runs fn in a transaction holding a lock on the user's row. Changes to the
+// user's address book from other connections, e.g. another server instance, wait for it
+// instead of both claiming the default; should one slip through anyway, the insert or update
+// fails on addresses_one_default_idx.
+func (repo *AddressRepo) withUserLock(ctx context.Context, userID int64, fn func(tx pgx.Tx) error) error {
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func clearDefault(ctx context.Context, tx pgx.Tx, userID, exceptID int64) error {
+	sql := `UPDATE addresses SET is_default = FALSE WHERE user_id = $1 AND id <> $2 AND is_default`
+	if _, err := tx.Exec(ctx, sql, userID, exceptID); err != nil {
+		return fmt.Errorf("failed to clear default address: %w", err)
+	}
+	return nil
+}
+
+// Create adds an address. It becomes the default if asked to, or if it is the user's first.
+func (repo *AddressRepo) Create(ctx context.Context, userID int64, a types.Address) (types.Address, error) {
+	err := repo.withUserLock(ctx, userID, func(tx pgx.Tx) error {
+		if !a.IsDefault {
+			var hasDefault bool
+			err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM addresses WHERE user_id = $1 AND is_default)`, userID).Scan(&hasDefault)
+			if err != nil {
+				return fmt.Errorf("failed to check default address: %w", err)
+			}
+			a.IsDefault = !hasDefault
+		}
+		if a.IsDefault {
+			if err := clearDefault(ctx, tx, userID, 0); err != nil {
+				return err
+			}
+		}
+
+		sql := `
+			INSERT INTO addresses (user_id, line1, city, state, zip_code, country, is_default)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+			RETURNING ` + addressColumns
+		var err error
+		a, err = scanAddress(tx.QueryRow(ctx, sql, userID, a.Line1, a.City, a.State, a.ZipCode, a.Country, a.IsDefault))
+		return err
+	})
+	return a, err
+}
+
+// Update replaces an address of the user. Making it the default unsets the previous one.
+// It returns pgx.ErrNoRows if the user has no such address.
+func (repo *AddressRepo) Update(ctx context.Context, userID, addressID int64, a types.Address) (types.Address, error) {
+	err := repo.withUserLock(ctx, userID, func(tx pgx.Tx) error {
+		if a.IsDefault {
+			if err := clearDefault(ctx, tx, userID, addressID); err != nil {
+				return err
+			}
+		}
+
+		sql := `
+			UPDATE addresses SET
+				line1 = $3, city = $4, state = NULLIF($5, ''), zip_code = NULLIF($6, ''), country = $7, is_default = $8
+			WHERE id = $1 AND user_id = $2
+			RETURNING ` + addressColumns
+		var err error
+		a, err = scanAddress(tx.QueryRow(ctx, sql, addressID, userID, a.Line1, a.City, a.State, a.ZipCode, a.Country, a.IsDefault))
+		return err
+	})
+	return a, err
+}
+
+// Delete removes an address of the user. It returns pgx.ErrNoRows if the user has no such
+// address and ErrInUse if an order in progress ships to it.
+func (repo *AddressRepo) Delete(ctx context.Context, userID, addressID int64) error {
+	return repo.withUserLock(ctx, userID, func(tx pgx.Tx) error {
+		var inUse bool
+		sql := `
+			SELECT EXISTS (
+				SELECT 1 FROM orders WHERE address_id = $1 AND status <> ALL($2)
+			)
+		`
+		if err := tx.QueryRow(ctx, sql, addressID, ClosedOrderStatuses).Scan(&inUse); err != nil {
+			return fmt.Errorf("failed to check orders: %w", err)
+		}
+		if inUse {
+			return ErrInUse
+		}
+
+		tag, err := tx.Exec(ctx, `DELETE FROM addresses WHERE id = $1 AND user_id = $2`, addressID, userID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
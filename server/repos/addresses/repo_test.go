@@ -0,0 +1,124 @@
+package addresses
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"ecom/server/types"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRepo *AddressRepo
+
+// TestMain sets up the db connection for all repo tests.
+func TestMain(m *testing.M) {
+	if err := godotenv.Load("../../../.env"); err != nil {
+		log.Println("Could not load .env file, will rely on environment variables.")
+	}
+
+	dburl := os.Getenv("DB_URL")
+	if dburl == "" {
+		log.Fatal("DB_URL is not set. Please provide it via .env file or environment variable.")
+	}
+
+	db, err := pgx.Connect(context.Background(), dburl)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	testRepo = NewAddressRepo(db)
+
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+// newUser inserts a user without addresses. Its addresses and orders go with it after the test.
+func newUser(t *testing.T) int64 {
+	t.Helper()
+	ctx := context.Background()
+	var id int64
+	email := fmt.Sprintf("address-test-%d@example.com", time.Now().UnixNano())
+	err := testRepo.DB.QueryRow(ctx, `INSERT INTO users (email, pass) VALUES ($1, '') RETURNING id`, email).Scan(&id)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		testRepo.DB.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	})
+	return id
+}
+
+func defaults(as []types.Address) []int64 {
+	var ids []int64
+	for _, a := range as {
+		if a.IsDefault {
+			ids = append(ids, a.ID)
+		}
+	}
+	return ids
+}
+
+func TestAddressRepo_SingleDefault(t *testing.T) {
+	ctx := context.Background()
+	userID := newUser(t)
+	home := types.Address{Line1: "1 Main St", City: "Springfield", Country: "US"}
+
+	first, err := testRepo.Create(ctx, userID, home)
+	require.NoError(t, err)
+	assert.True(t, first.IsDefault, "the first address becomes the default")
+
+	second, err := testRepo.Create(ctx, userID, home)
+	require.NoError(t, err)
+	assert.False(t, second.IsDefault)
+
+	third, err := testRepo.Create(ctx, userID, types.Address{Line1: "2 Side St", City: "Springfield", Country: "US", IsDefault: true})
+	require.NoError(t, err)
+	as, err := testRepo.List(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{third.ID}, defaults(as), "a new default replaces the old one")
+	assert.Equal(t, third.ID, as[0].ID, "the default is listed first")
+
+	second.IsDefault = true
+	_, err = testRepo.Update(ctx, userID, second.ID, second)
+	require.NoError(t, err)
+	as, err = testRepo.List(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{second.ID}, defaults(as))
+
+	_, err = testRepo.Update(ctx, newUser(t), second.ID, second)
+	assert.ErrorIs(t, err, pgx.ErrNoRows, "other users' addresses can't be changed")
+}
+
+func TestAddressRepo_Delete(t *testing.T) {
+	ctx := context.Background()
+	userID := newUser(t)
+	a, err := testRepo.Create(ctx, userID, types.Address{Line1: "1 Main St", City: "Springfield", Country: "US"})
+	require.NoError(t, err)
+
+	order := func(status string) {
+		_, err := testRepo.DB.Exec(ctx, `
+			INSERT INTO orders (user_id, address_id, status, total_amount, payment_method)
+			VALUES ($1, $2, $3, 10, 'card')
+		`, userID, a.ID, status)
+		require.NoError(t, err)
+	}
+
+	order("completed")
+	order("cancelled")
+	order("processing")
+	assert.ErrorIs(t, testRepo.Delete(ctx, userID, a.ID), ErrInUse, "orders in any status but the closed ones need the address")
+
+	_, err = testRepo.DB.Exec(ctx, `UPDATE orders SET status = 'completed' WHERE address_id = $1`, a.ID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, testRepo.Delete(ctx, newUser(t), a.ID), pgx.ErrNoRows)
+	require.NoError(t, testRepo.Delete(ctx, userID, a.ID))
+	assert.ErrorIs(t, testRepo.Delete(ctx, userID, a.ID), pgx.ErrNoRows)
+}
@@ -0,0 +1,104 @@
+package apikeys
+
+import (
+	"context"
+	"ecom/server/types"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type APIKeyRepo struct {
+	DB *pgx.Conn
+}
+
+func NewAPIKeyRepo(db *pgx.Conn) *APIKeyRepo {
+	return &APIKeyRepo{DB: db}
+}
+
+// StoredKey is a key as needed to authenticate a request with it.
+type StoredKey struct {
+	types.APIKey
+	Hash string
+	Role string // Current role of the key's owner.
+}
+
+const keyColumns = `k.id, k.user_id, k.name, k.prefix, k.scopes, k.expires_at, k.last_used_at, k.revoked_at, k.created_at`
+
+func scanKey(row pgx.Row, extra ...any) (types.APIKey, error) {
+	var k types.APIKey
+	dest := append([]any{&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt}, extra...)
+	err := row.Scan(dest...)
+	return k, err
+}
+
+// Create stores k under the hash of its secret and returns it with its ID and CreatedAt set.
+func (repo *APIKeyRepo) Create(ctx context.Context, k types.APIKey, keyHash string) (types.APIKey, error) {
+	sql := `
+		INSERT INTO api_keys AS k (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + keyColumns
+	return scanKey(repo.DB.QueryRow(ctx, sql, k.UserID, k.Name, k.Prefix, keyHash, k.Scopes, k.ExpiresAt))
+}
+
+// GetActiveByPrefix returns an unrevoked, unexpired key. It returns pgx.ErrNoRows if there is none.
+func (repo *APIKeyRepo) GetActiveByPrefix(ctx context.Context, prefix string) (StoredKey, error) {
+	var sk StoredKey
+	sql := `
+		SELECT ` + keyColumns + `, k.key_hash, u.role
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.prefix = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())
+	`
+	k, err := scanKey(repo.DB.QueryRow(ctx, sql, prefix), &sk.Hash, &sk.Role)
+	sk.APIKey = k
+	return sk, err
+}
+
+// List returns the keys of a user, or of everyone if userID is 0, newest first.
+func (repo *APIKeyRepo) List(ctx context.Context, userID int64) ([]types.APIKey, error) {
+	sql := `
+		SELECT ` + keyColumns + `
+		FROM api_keys k
+		WHERE $1 = 0 OR k.user_id = $1
+		ORDER BY k.id DESC
+	`
+	rows, err := repo.DB.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	ks := []types.APIKey{}
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key row: %w", err)
+		}
+		ks = append(ks, k)
+	}
+	return ks, rows.Err()
+}
+
+// Revoke revokes a key. It returns pgx.ErrNoRows if there is no such active key.
+func (repo *APIKeyRepo) Revoke(ctx context.Context, keyID int64) error {
+	tag, err := repo.DB.Exec(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, keyID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Touch records a use of a key. It writes at most once a minute per key,
+// so busy integrations don't turn every request into a write.
+func (repo *APIKeyRepo) Touch(ctx context.Context, keyID int64) error {
+	sql := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	_, err := repo.DB.Exec(ctx, sql, keyID)
+	return err
+}
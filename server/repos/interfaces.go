@@ -2,11 +2,109 @@ package repos
 
 import (
 	"context"
+	"ecom/server/repos/apikeys"
 	"ecom/server/repos/products"
+	"ecom/server/repos/users"
 	"ecom/server/types"
+	"time"
 )
 
 type IProductRepo interface {
 	Get(ctx context.Context, productID int64) (types.Product, error)
 	GetAll(ctx context.Context, options products.GetAllOptions) (products.GetAllResult, error)
+	Create(ctx context.Context, req types.ProductRequest) (int64, error)
+	Update(ctx context.Context, productID int64, req types.ProductRequest) error
+	Delete(ctx context.Context, productID int64) error
+	Suggest(ctx context.Context, q string, limit int) (types.Suggestions, error)
+}
+
+type ICategoryRepo interface {
+	List(ctx context.Context) ([]types.Category, error)
+	Create(ctx context.Context, name string, parentID *int64) (types.Category, error)
+	Update(ctx context.Context, categoryID int64, name string, parentID *int64) (types.Category, error)
+	Delete(ctx context.Context, categoryID int64, dryRun bool) (types.CategoryDeletion, error)
+}
+
+type ISearchRepo interface {
+	ListSynonyms(ctx context.Context) ([]types.SynonymGroup, error)
+	CreateSynonyms(ctx context.Context, terms []string) (types.SynonymGroup, error)
+	UpdateSynonyms(ctx context.Context, groupID int64, terms []string) (types.SynonymGroup, error)
+	DeleteSynonyms(ctx context.Context, groupID int64) error
+	FindSynonyms(ctx context.Context, phrases []string) ([][]string, error)
+	SuggestSpellings(ctx context.Context, words []string) ([]string, error)
+	RecordSearch(ctx context.Context, query, correctedQuery string, resultCount int, tokenHash string) error
+	RecordClick(ctx context.Context, tokenHash string, productID int64, searchedAfter time.Time) error
+	SearchAnalytics(ctx context.Context, since time.Time, limit int) (types.SearchAnalytics, error)
+}
+
+type IPermissionRepo interface {
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+	UserHasPermission(ctx context.Context, userID int64, permission string) (bool, error)
+	ListPermissions(ctx context.Context) ([]types.Permission, error)
+	ListRoles(ctx context.Context) ([]types.Role, error)
+	CreateRole(ctx context.Context, name, description string) (types.Role, error)
+	SetRolePermissions(ctx context.Context, role string, permissions []string) error
+	AssignUserRole(ctx context.Context, userID int64, role string) error
+}
+
+type IAPIKeyRepo interface {
+	Create(ctx context.Context, k types.APIKey, keyHash string) (types.APIKey, error)
+	GetActiveByPrefix(ctx context.Context, prefix string) (apikeys.StoredKey, error)
+	List(ctx context.Context, userID int64) ([]types.APIKey, error)
+	Revoke(ctx context.Context, keyID int64) error
+	Touch(ctx context.Context, keyID int64) error
+}
+
+type IAddressRepo interface {
+	List(ctx context.Context, userID int64) ([]types.Address, error)
+	Create(ctx context.Context, userID int64, a types.Address) (types.Address, error)
+	Update(ctx context.Context, userID, addressID int64, a types.Address) (types.Address, error)
+	Delete(ctx context.Context, userID, addressID int64) error
+}
+
+type IUserRepo interface {
+	Create(ctx context.Context, email, passHash string) (types.User, error)
+	Get(ctx context.Context, userID int64) (types.User, error)
+	GetByEmail(ctx context.Context, email string) (types.User, error)
+	List(ctx context.Context, afterID int64, limit int) ([]types.User, error)
+	CreateServiceAccount(ctx context.Context, email, role string) (types.User, error)
+	UpdatePassword(ctx context.Context, userID int64, passHash string) error
+	UpdateProfile(ctx context.Context, userID int64, name, phone *string) (types.User, error)
+	ChangeEmail(ctx context.Context, tokenHash string) error
+	MarkEmailVerified(ctx context.Context, userID int64) error
+	Export(ctx context.Context, userID int64) (types.AccountExport, error)
+	Anonymize(ctx context.Context, tokenHash string) error
+
+	CreateRefreshToken(ctx context.Context, t users.RefreshToken, tokenHash string) (users.RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (users.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID int64, next users.RefreshToken, newHash string) (users.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int64) error
+	ListSessions(ctx context.Context, userID int64) ([]types.Session, error)
+	RevokeSession(ctx context.Context, userID int64, familyID string) error
+	TouchSession(ctx context.Context, familyID string) (bool, error)
+
+	CreateUserToken(ctx context.Context, userID int64, purpose, tokenHash, payload string, expiresAt time.Time) error
+	ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (userID int64, payload string, err error)
+	InvalidateUserTokens(ctx context.Context, userID int64, purpose string) error
+	GetActiveUserTokenPayload(ctx context.Context, userID int64, purpose string) (string, error)
+
+	GetLockedUntil(ctx context.Context, scope, key string) (time.Time, error)
+	RecordLoginFailure(ctx context.Context, scope, key string, window time.Duration) (int, error)
+	LockLogin(ctx context.Context, scope, key string, until time.Time) error
+	ClearLoginFailures(ctx context.Context, scope, key string) error
+
+	GetTOTP(ctx context.Context, userID int64) (users.TOTPState, error)
+	SetTOTPSecret(ctx context.Context, userID int64, secret string) error
+	EnableTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	DisableTOTP(ctx context.Context, userID int64) error
+
+	CreateOIDCState(ctx context.Context, stateHash, provider, nonce, codeVerifier string, expiresAt time.Time) error
+	ConsumeOIDCState(ctx context.Context, stateHash, provider string) (nonce, codeVerifier string, err error)
+	GetByIdentity(ctx context.Context, provider, subject string) (types.User, error)
+	LinkIdentity(ctx context.Context, userID int64, provider, subject, email string) error
+	CreateWithIdentity(ctx context.Context, email, name string, emailVerified bool, provider, subject string) (types.User, error)
 }
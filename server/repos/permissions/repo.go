@@ -0,0 +1,153 @@
+package permissions
+
+import (
+	"context"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnknownPermission is returned when a permission name doesn't exist.
+var ErrUnknownPermission = errors.New("unknown permission")
+
+type PermissionRepo struct {
+	DB *pgx.Conn
+}
+
+func NewPermissionRepo(db *pgx.Conn) *PermissionRepo {
+	return &PermissionRepo{DB: db}
+}
+
+// HasPermission reports whether the role is granted the permission.
+func (repo *PermissionRepo) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	var ok bool
+	sql := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM role_permissions rp
+			JOIN roles r ON r.id = rp.role_id
+			JOIN permissions p ON p.id = rp.permission_id
+			WHERE r.name = $1 AND p.name = $2
+		)
+	`
+	err := repo.DB.QueryRow(ctx, sql, role, permission).Scan(&ok)
+	return ok, err
+}
+
+// UserHasPermission reports whether the role the user holds now is granted the permission.
+func (repo *PermissionRepo) UserHasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	var ok bool
+	sql := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM users u
+			JOIN roles r ON r.name = u.role
+			JOIN role_permissions rp ON rp.role_id = r.id
+			JOIN permissions p ON p.id = rp.permission_id
+			WHERE u.id = $1 AND p.name = $2
+		)
+	`
+	err := repo.DB.QueryRow(ctx, sql, userID, permission).Scan(&ok)
+	return ok, err
+}
+
+func (repo *PermissionRepo) ListPermissions(ctx context.Context) ([]types.Permission, error) {
+	rows, err := repo.DB.Query(ctx, `SELECT id, name, COALESCE(description, '') FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query permissions: %w", err)
+	}
+	defer rows.Close()
+
+	perms := make([]types.Permission, 0)
+	for rows.Next() {
+		var p types.Permission
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan permission row: %w", err)
+		}
+		perms = append(perms, p)
+	}
+	return perms, rows.Err()
+}
+
+// ListRoles returns every role with the names of its permissions.
+func (repo *PermissionRepo) ListRoles(ctx context.Context) ([]types.Role, error) {
+	sql := `
+		SELECT
+			r.id,
+			r.name,
+			COALESCE(r.description, ''),
+			COALESCE(ARRAY_AGG(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}') AS permissions
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN permissions p ON p.id = rp.permission_id
+		GROUP BY r.id
+		ORDER BY r.name
+	`
+	rows, err := repo.DB.Query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make([]types.Role, 0)
+	for rows.Next() {
+		var r types.Role
+		if err := rows.Scan(&r.ID, &r.Name, &r.Description, &r.Permissions); err != nil {
+			return nil, fmt.Errorf("failed to scan role row: %w", err)
+		}
+		roles = append(roles, r)
+	}
+	return roles, rows.Err()
+}
+
+func (repo *PermissionRepo) CreateRole(ctx context.Context, name, description string) (types.Role, error) {
+	r := types.Role{Name: name, Description: description, Permissions: []string{}}
+	err := repo.DB.QueryRow(ctx, `INSERT INTO roles (name, description) VALUES ($1, $2) RETURNING id`, name, description).Scan(&r.ID)
+	return r, err
+}
+
+// SetRolePermissions replaces the permissions of a role. It returns pgx.ErrNoRows if the
+// role doesn't exist and ErrUnknownPermission if any permission name is unknown.
+func (repo *PermissionRepo) SetRolePermissions(ctx context.Context, role string, permissions []string) error {
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var roleID int64
+	if err := tx.QueryRow(ctx, `SELECT id FROM roles WHERE name = $1 FOR UPDATE`, role).Scan(&roleID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return fmt.Errorf("failed to clear role permissions: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO role_permissions (role_id, permission_id)
+		SELECT $1, p.id FROM permissions p WHERE p.name = ANY($2)
+	`, roleID, permissions)
+	if err != nil {
+		return fmt.Errorf("failed to grant role permissions: %w", err)
+	}
+	if int(tag.RowsAffected()) != len(permissions) {
+		return ErrUnknownPermission
+	}
+
+	return tx.Commit(ctx)
+}
+
+// AssignUserRole sets the role of a user. It returns pgx.ErrNoRows if the user doesn't exist.
+func (repo *PermissionRepo) AssignUserRole(ctx context.Context, userID int64, role string) error {
+	tag, err := repo.DB.Exec(ctx, `UPDATE users SET role = $2 WHERE id = $1`, userID, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
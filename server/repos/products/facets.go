@@ -0,0 +1,115 @@
+package products
+
+import (
+	"context"
+	"ecom/server/types"
+	"fmt"
+)
+
+// priceBounds split the price facet into ranges: under 25, 25 to 50, ... and 1000 and more.
+var priceBounds = []float64{25, 50, 100, 250, 500, 1000}
+
+// ratingFacetScores are the minimum average scores counted by the rating facet.
+var ratingFacetScores = []int{4, 3, 2, 1}
+
+// facets counts the products matching the filters per category, price range and minimum rating.
+// Each count ignores the filter on its own facet. Like the category filter, a category counts the
+// products of its subcategories too.
+func (repo *ProductRepo) facets(ctx context.Context, opts FiltersOptions) (*types.Facets, error) {
+	fs := &types.Facets{Categories: []types.CategoryFacet{}}
+
+	// tree pairs every category with itself and each of its subcategories.
+	f := buildFilters(opts, facetCategory)
+	sql := fmt.Sprintf(`
+		WITH RECURSIVE tree AS (
+			SELECT id AS root_id, id FROM categories
+			UNION
+			SELECT tree.root_id, sc.id FROM categories sc JOIN tree ON sc.parent_id = tree.id
+		)
+		SELECT c.id, c.name, COUNT(*)
+		FROM (%s) m
+		JOIN tree ON tree.id = m.category_id
+		JOIN categories c ON c.id = tree.root_id
+		GROUP BY c.id
+		ORDER BY COUNT(*) DESC, c.name
+	`, f.matchingSQL())
+	rows, err := repo.DB.Query(ctx, sql, f.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count products per category: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c types.CategoryFacet
+		if err := rows.Scan(&c.ID, &c.Name, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan category facet row: %w", err)
+		}
+		fs.Categories = append(fs.Categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category facet rows: %w", err)
+	}
+
+	// width_bucket numbers the ranges from 0, below the first bound, to len(priceBounds).
+	f = buildFilters(opts, facetPrice)
+	sql = fmt.Sprintf(`
+		SELECT width_bucket(m.price, %s::numeric[]) AS bucket, COUNT(*)
+		FROM (%s) m
+		GROUP BY bucket
+	`, f.arg(priceBounds), f.matchingSQL())
+	rows, err = repo.DB.Query(ctx, sql, f.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count products per price range: %w", err)
+	}
+	defer rows.Close()
+	fs.Prices = priceFacets()
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan price facet row: %w", err)
+		}
+		fs.Prices[bucket].Count = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price facet rows: %w", err)
+	}
+
+	f = buildFilters(opts, facetRating)
+	sql = fmt.Sprintf(`
+		SELECT s.min_score, COUNT(m.id)
+		FROM unnest(%s::int[]) AS s(min_score)
+		LEFT JOIN (%s) m ON m.avg_score >= s.min_score
+		GROUP BY s.min_score
+		ORDER BY s.min_score DESC
+	`, f.arg(ratingFacetScores), f.matchingSQL())
+	rows, err = repo.DB.Query(ctx, sql, f.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count products per rating: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r types.RatingFacet
+		if err := rows.Scan(&r.MinScore, &r.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan rating facet row: %w", err)
+		}
+		fs.Ratings = append(fs.Ratings, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rating facet rows: %w", err)
+	}
+	return fs, nil
+}
+
+// priceFacets returns the empty price ranges, in the order width_bucket numbers them.
+func priceFacets() []types.PriceFacet {
+	ps := make([]types.PriceFacet, len(priceBounds)+1)
+	for i := range ps {
+		if i > 0 {
+			ps[i].Min = priceBounds[i-1]
+		}
+		if i < len(priceBounds) {
+			max := priceBounds[i]
+			ps[i].Max = &max
+		}
+	}
+	return ps
+}
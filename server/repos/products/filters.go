@@ -0,0 +1,93 @@
+package products
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Facets, each named after the filter it ignores when counting.
+const (
+	facetCategory = "category"
+	facetPrice    = "price"
+	facetRating   = "rating"
+)
+
+// filterQuery holds the SQL conditions of a product filter with their arguments, numbered from $1.
+// where applies to products, having to their group with ratings joined as r.
+type filterQuery struct {
+	args   []any
+	where  []string
+	having string
+	rank   string // Rank of the search match, empty without a search.
+}
+
+// arg adds an argument and returns its placeholder.
+func (f *filterQuery) arg(v any) string {
+	f.args = append(f.args, v)
+	return fmt.Sprintf("$%d", len(f.args))
+}
+
+// whereSQL returns the WHERE clause of the filters and the extra conditions, if any.
+func (f *filterQuery) whereSQL(extra ...string) string {
+	conds := append(append([]string{}, f.where...), extra...)
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, " AND ")
+}
+
+func (f *filterQuery) havingSQL() string {
+	if f.having == "" {
+		return ""
+	}
+	return "HAVING " + f.having
+}
+
+// matchingSQL selects the id, price, category and average score of the matching products.
+func (f *filterQuery) matchingSQL() string {
+	return fmt.Sprintf(`
+		SELECT p.id, p.price, p.category_id, COALESCE(AVG(r.score), 0) AS avg_score
+		FROM products p
+		LEFT JOIN ratings r ON r.product_id = p.id
+		%s
+		GROUP BY p.id
+		%s
+	`, f.whereSQL(), f.havingSQL())
+}
+
+// buildFilters turns the filter options into SQL, leaving out the filter of the skipped facet, if any.
+func buildFilters(opts FiltersOptions, skip string) *filterQuery {
+	f := &filterQuery{}
+	if skip != facetPrice {
+		if opts.PriceMin != nil {
+			f.where = append(f.where, "p.price >= "+f.arg(*opts.PriceMin))
+		}
+		if opts.PriceMax != nil {
+			f.where = append(f.where, "p.price <= "+f.arg(*opts.PriceMax))
+		}
+	}
+	if opts.SearchString != nil {
+		// websearch_to_tsquery accepts what users type into a search box: words, "quoted phrases", -exclusions and or.
+		var queries []string
+		for _, q := range append([]string{*opts.SearchString}, opts.SearchAlternatives...) {
+			queries = append(queries, fmt.Sprintf("websearch_to_tsquery('english', %s)", f.arg(q)))
+		}
+		query := "(" + strings.Join(queries, " || ") + ")"
+		f.where = append(f.where, "p.search_vector @@ "+query)
+		f.rank = fmt.Sprintf("ts_rank(p.search_vector, %s)", query)
+	}
+	if len(opts.CategoryIDs) > 0 && skip != facetCategory {
+		f.where = append(f.where, fmt.Sprintf(`p.category_id IN (
+			WITH RECURSIVE sub AS (
+				SELECT id FROM categories WHERE id = ANY(%s)
+				UNION
+				SELECT sc.id FROM categories sc JOIN sub ON sc.parent_id = sub.id
+			)
+			SELECT id FROM sub
+		)`, f.arg(opts.CategoryIDs)))
+	}
+	if opts.MinScore != nil && skip != facetRating {
+		f.having = "COALESCE(AVG(r.score), 0) >= " + f.arg(*opts.MinScore)
+	}
+	return f
+}
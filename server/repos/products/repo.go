@@ -1,11 +1,14 @@
 package products
 
 import (
+	"cmp"
 	"context"
 	"ecom/server/types"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -19,36 +22,46 @@ func NewProductRepo(db *pgx.Conn) *ProductRepo {
 }
 
 type PaginationOptions struct {
-	Cursor  []string // [sort_key, tie_breaker_id] for keyset pagination.
+	Cursor  []string // [sort_key, tie_breaker_id] for keyset pagination, then the corrected search if one was run.
 	PageNum int
 }
 type SortOptions struct {
-	SortBy string // "price" or "created_at"
+	SortBy string // "price", "created_at" or "relevance" (only with a search)
 	Order  string // "asc" or "desc"
 }
 type FiltersOptions struct {
-	PriceMin     *float64
-	PriceMax     *float64
-	SearchString *string
-	MinScore     *int
+	PriceMin           *float64
+	PriceMax           *float64
+	SearchString       *string
+	SearchAlternatives []string // Variants of SearchString, e.g. with synonyms; matching one is enough.
+	MinScore           *int
+	CategoryIDs        []int64 // Products in any of these categories or their subcategories.
 }
 type GetAllOptions struct {
 	Filters    FiltersOptions
 	Pagination PaginationOptions
 	Sort       SortOptions
+	Facets     bool // Also count the matching products per category, price and rating.
 }
 
 type GetAllResult struct {
-	Products   []types.MiniProduct
-	TotalPages int
-	TotalCount int
+	Products       []types.MiniProduct
+	TotalPages     int
+	TotalCount     int
+	CorrectedQuery string        `json:",omitempty"` // The search actually run, when the requested one was misspelled
+	NextCursor     string        `json:",omitempty"` // Fetches the next page; empty when this page isn't full
+	SearchToken    string        `json:",omitempty"` // Sent with the clicks on the results, to tie them to this search
+	Facets         *types.Facets `json:",omitempty"`
 }
 
 // MapRequestToGetAllOptions converts the request to repo options, with defaults.
 func MapRequestToGetAllOptions(req *types.GetProductsRequest) GetAllOptions {
+	// Search results are best ranked by relevance unless asked otherwise.
 	sortBy := "created_at"
 	if req.SortBy != "" {
 		sortBy = req.SortBy
+	} else if req.SearchString != nil {
+		sortBy = "relevance"
 	}
 	order := "desc"
 	if req.Order != "" {
@@ -61,6 +74,7 @@ func MapRequestToGetAllOptions(req *types.GetProductsRequest) GetAllOptions {
 			PriceMax:     req.PriceMax,
 			SearchString: req.SearchString,
 			MinScore:     req.MinScore,
+			CategoryIDs:  req.CategoryIDs,
 		},
 		Pagination: PaginationOptions{
 			PageNum: req.PageNum, // Repo applies a default if this is 0
@@ -70,6 +84,7 @@ func MapRequestToGetAllOptions(req *types.GetProductsRequest) GetAllOptions {
 			SortBy: sortBy,
 			Order:  order,
 		},
+		Facets: req.Facets,
 	}
 }
 func (repo *ProductRepo) Get(ctx context.Context, productID int64) (types.Product, error) {
@@ -78,11 +93,21 @@ func (repo *ProductRepo) Get(ctx context.Context, productID int64) (types.Produc
 		SELECT
 			p.id,
 			p.name,
-			p.description,
+			COALESCE(p.description, ''),
 			p.price,
 			p.created_at,
-			c.id AS category_id,
-			c.name AS category_name,
+			COALESCE(c.id, 0) AS category_id,
+			COALESCE(c.name, '') AS category_name,
+			COALESCE((
+				WITH RECURSIVE path AS (
+					SELECT id, name, parent_id, 0 AS depth FROM categories WHERE id = p.category_id
+					UNION ALL
+					SELECT pc.id, pc.name, pc.parent_id, path.depth + 1
+					FROM categories pc JOIN path ON pc.id = path.parent_id
+					WHERE path.depth < 32
+				)
+				SELECT JSON_AGG(JSON_BUILD_OBJECT('id', id, 'name', name) ORDER BY depth DESC) FROM path
+			), '[]') AS category_path,
 			COALESCE(
 				JSON_AGG(
 					JSON_BUILD_OBJECT('url', pi.url, 'alt_text', pi.alt_text)
@@ -100,86 +125,60 @@ func (repo *ProductRepo) Get(ctx context.Context, productID int64) (types.Produc
 	`
 	r := repo.DB.QueryRow(ctx, sql, productID)
 
-	args := []any{&p.ID, &p.Name, &p.Description, &p.Price, &p.CreatedAt, &p.CategoryData.ID, &p.CategoryData.Name, &p.Images, &p.AvgRating}
+	args := []any{&p.ID, &p.Name, &p.Description, &p.Price, &p.CreatedAt, &p.CategoryData.ID, &p.CategoryData.Name, &p.CategoryData.Path, &p.Images, &p.AvgRating}
 	if err := r.Scan(args...); err != nil {
 		return p, err
 	}
 	return p, nil
 }
 
-func (repo *ProductRepo) GetAll(ctx context.Context, options GetAllOptions) (GetAllResult, error) {
-	res := GetAllResult{
-		Products: make([]types.MiniProduct, 0),
+// sortColumn returns the SQL expression products are sorted by. Relevance is the rank of the
+// search match and falls back to the creation time without a search.
+func sortColumn(sortBy, rankSQL string) string {
+	switch {
+	case sortBy == "price":
+		return "p.price"
+	case sortBy == "relevance" && rankSQL != "":
+		return rankSQL
+	default:
+		return "p.created_at"
 	}
+}
 
-	var args, countArgs []any
-	var mainWhereClauses, filterWhereClauses []string
-
-	// These filter clauses apply to both the main query and the count query.
-	if options.Filters.PriceMin != nil {
-		args = append(args, *options.Filters.PriceMin)
-		filterWhereClauses = append(filterWhereClauses, fmt.Sprintf("p.price >= $%d", len(args)))
-	}
-	if options.Filters.PriceMax != nil {
-		args = append(args, *options.Filters.PriceMax)
-		filterWhereClauses = append(filterWhereClauses, fmt.Sprintf("p.price <= $%d", len(args)))
-	}
-	if options.Filters.SearchString != nil {
-		searchArg := "%" + *options.Filters.SearchString + "%"
-		args = append(args, searchArg)
-		filterWhereClauses = append(filterWhereClauses, fmt.Sprintf("(p.name ILIKE $%d OR p.description ILIKE $%d)", len(args), len(args)))
+// cursorValue formats the sort value of a product as a cursor sort key, to match sortColumn.
+func cursorValue(sortBy, rankSQL string, p types.MiniProduct) string {
+	switch {
+	case sortBy == "price":
+		return strconv.FormatFloat(p.Price, 'f', -1, 64)
+	case sortBy == "relevance" && rankSQL != "":
+		return strconv.FormatFloat(float64(p.Relevance), 'g', -1, 32)
+	default:
+		return p.CreatedAt.Format(time.RFC3339Nano)
 	}
+}
 
-	// The count query uses only the filter arguments.
-	countArgs = append(countArgs, args...)
-	countWhereSQL := ""
-	if len(filterWhereClauses) > 0 {
-		countWhereSQL = "WHERE " + strings.Join(filterWhereClauses, " AND ")
+func (repo *ProductRepo) GetAll(ctx context.Context, options GetAllOptions) (GetAllResult, error) {
+	res := GetAllResult{
+		Products: make([]types.MiniProduct, 0),
 	}
 
 	// The main query's WHERE clause starts with the filters and may have the cursor condition added.
-	mainWhereClauses = filterWhereClauses
-
-	if len(options.Pagination.Cursor) == 2 { // Cursor pagination is active.
+	filters := buildFilters(options.Filters, "")
+	var cursorSQL []string
+	if len(options.Pagination.Cursor) >= 2 { // Cursor pagination is active.
 		sortValue := options.Pagination.Cursor[0]
 		idValue := options.Pagination.Cursor[1]
 
-		sortByField := "p.created_at"
-		if options.Sort.SortBy == "price" {
-			sortByField = "p.price"
-		}
+		sortByField := sortColumn(options.Sort.SortBy, filters.rank)
 
 		operator := ">"
 		if strings.ToLower(options.Sort.Order) == "desc" {
 			operator = "<"
 		}
-
-		// Append cursor args to the main query args, then create the clause.
-		args = append(args, sortValue, idValue)
-		mainWhereClauses = append(mainWhereClauses, fmt.Sprintf("(%s, p.id) %s ($%d, $%d)", sortByField, operator, len(args)-1, len(args)))
-	}
-
-	whereSQL := ""
-	if len(mainWhereClauses) > 0 {
-		whereSQL = "WHERE " + strings.Join(mainWhereClauses, " AND ")
-	}
-
-	havingSQL := ""
-	countHavingSQL := ""
-	if options.Filters.MinScore != nil {
-		scoreArg := *options.Filters.MinScore
-
-		args = append(args, scoreArg)
-		havingSQL = fmt.Sprintf("HAVING COALESCE(AVG(r.score), 0) >= $%d", len(args))
-
-		countArgs = append(countArgs, scoreArg)
-		countHavingSQL = fmt.Sprintf("HAVING COALESCE(AVG(r.score), 0) >= $%d", len(countArgs))
+		cursorSQL = append(cursorSQL, fmt.Sprintf("(%s, p.id) %s (%s, %s)", sortByField, operator, filters.arg(sortValue), filters.arg(idValue)))
 	}
 
-	sortBy := "p.created_at"
-	if options.Sort.SortBy == "price" {
-		sortBy = "p.price"
-	}
+	sortBy := sortColumn(options.Sort.SortBy, filters.rank)
 	order := "DESC"
 	if strings.ToLower(options.Sort.Order) == "asc" {
 		order = "ASC"
@@ -190,8 +189,7 @@ func (repo *ProductRepo) GetAll(ctx context.Context, options GetAllOptions) (Get
 	if options.Pagination.PageNum > 0 {
 		limit = options.Pagination.PageNum
 	}
-	args = append(args, limit)
-	limitSQL := fmt.Sprintf("LIMIT $%d", len(args))
+	limitSQL := "LIMIT " + filters.arg(limit)
 
 	mainQuerySQL := fmt.Sprintf(`
 		SELECT
@@ -199,8 +197,8 @@ func (repo *ProductRepo) GetAll(ctx context.Context, options GetAllOptions) (Get
 			p.name,
 			p.price,
 			p.created_at,
-			c.id AS category_id,
-			c.name AS category_name,
+			COALESCE(c.id, 0) AS category_id,
+			COALESCE(c.name, '') AS category_name,
 			COALESCE(
 				(SELECT json_build_object('url', pi.url, 'alt_text', pi.alt_text)
 				 FROM product_images pi
@@ -209,7 +207,8 @@ func (repo *ProductRepo) GetAll(ctx context.Context, options GetAllOptions) (Get
 				 LIMIT 1),
 				'{}'
 			) AS image,
-			COALESCE(AVG(r.score), 0) AS average_rating
+			COALESCE(AVG(r.score), 0) AS average_rating,
+			%s AS relevance
 		FROM products p
 		LEFT JOIN product_images pi ON p.id = pi.product_id
 		LEFT JOIN categories c ON c.id = p.category_id
@@ -219,9 +218,9 @@ func (repo *ProductRepo) GetAll(ctx context.Context, options GetAllOptions) (Get
 		%s
 		%s
 		%s
-	`, whereSQL, havingSQL, orderSQL, limitSQL)
+	`, cmp.Or(filters.rank, "0::real"), filters.whereSQL(cursorSQL...), filters.havingSQL(), orderSQL, limitSQL)
 
-	rows, err := repo.DB.Query(ctx, mainQuerySQL, args...)
+	rows, err := repo.DB.Query(ctx, mainQuerySQL, filters.args...)
 	if err != nil {
 		return res, fmt.Errorf("failed to query products: %w", err)
 	}
@@ -231,7 +230,7 @@ func (repo *ProductRepo) GetAll(ctx context.Context, options GetAllOptions) (Get
 		var p types.MiniProduct
 		var avgRating float64
 
-		scanArgs := []any{&p.ID, &p.Name, &p.Price, &p.CreatedAt, &p.CategoryData.ID, &p.CategoryData.Name, &p.Image, &avgRating}
+		scanArgs := []any{&p.ID, &p.Name, &p.Price, &p.CreatedAt, &p.CategoryData.ID, &p.CategoryData.Name, &p.Image, &avgRating, &p.Relevance}
 		if err := rows.Scan(scanArgs...); err != nil {
 			return res, fmt.Errorf("failed to scan product row: %w", err)
 		}
@@ -242,25 +241,21 @@ func (repo *ProductRepo) GetAll(ctx context.Context, options GetAllOptions) (Get
 	if err := rows.Err(); err != nil {
 		return res, fmt.Errorf("error iterating product rows: %w", err)
 	}
+	if n := len(res.Products); n > 0 && n == limit {
+		last := res.Products[n-1]
+		res.NextCursor = cursorValue(options.Sort.SortBy, filters.rank, last) + "," + strconv.FormatInt(last.ID, 10)
+	}
 
 	// The total count query respects filters but ignores pagination (cursor/limit).
+	count := buildFilters(options.Filters, "")
 	var countSQL string
-	if options.Filters.MinScore != nil {
-		countSQL = fmt.Sprintf(`
-			SELECT COUNT(*) FROM (
-				SELECT 1
-				FROM products p
-				LEFT JOIN ratings r ON r.product_id = p.id
-				%s
-				GROUP BY p.id
-				%s
-			) AS sub
-		`, countWhereSQL, countHavingSQL)
+	if count.having != "" {
+		countSQL = fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS sub", count.matchingSQL())
 	} else {
-		countSQL = fmt.Sprintf("SELECT COUNT(p.id) FROM products p %s", countWhereSQL)
+		countSQL = fmt.Sprintf("SELECT COUNT(p.id) FROM products p %s", count.whereSQL())
 	}
 
-	err = repo.DB.QueryRow(ctx, countSQL, countArgs...).Scan(&res.TotalCount)
+	err = repo.DB.QueryRow(ctx, countSQL, count.args...).Scan(&res.TotalCount)
 	if err != nil {
 		return res, fmt.Errorf("failed to count products: %w", err)
 	}
@@ -269,5 +264,10 @@ func (repo *ProductRepo) GetAll(ctx context.Context, options GetAllOptions) (Get
 		res.TotalPages = int(math.Ceil(float64(res.TotalCount) / float64(limit)))
 	}
 
+	if options.Facets {
+		if res.Facets, err = repo.facets(ctx, options.Filters); err != nil {
+			return res, err
+		}
+	}
 	return res, nil
 }
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/jackc/pgx/v5"
@@ -140,6 +141,38 @@ func TestProductRepo_GetAll(t *testing.T) {
 				assert.Equal(t, "Stealth Drone Pro", res.Products[0].Name)
 			},
 		},
+		{
+			name: "Search is stemmed and ranked by relevance",
+			options: GetAllOptions{
+				Filters: FiltersOptions{SearchString: stringPtr("headphone")},
+				Sort:    SortOptions{SortBy: "relevance", Order: "desc"},
+			},
+			asserter: func(t *testing.T, res GetAllResult) {
+				require.NotEmpty(t, res.Products)
+				assert.Equal(t, "SilentBeat Pro Headphones", res.Products[0].Name, "A match in the name ranks above matches in descriptions")
+				for i := 1; i < len(res.Products); i++ {
+					assert.GreaterOrEqual(t, res.Products[i-1].Relevance, res.Products[i].Relevance)
+				}
+			},
+		},
+		{
+			name: "Filter by several categories",
+			options: GetAllOptions{
+				Filters:    FiltersOptions{CategoryIDs: []int64{1, 3}},
+				Pagination: PaginationOptions{PageNum: 100},
+			},
+			asserter: func(t *testing.T, res GetAllResult) {
+				require.NotEmpty(t, res.Products)
+				for _, p := range res.Products {
+					assert.Contains(t, []int64{1, 3}, p.CategoryData.ID)
+				}
+				var count int
+				err := testRepo.DB.QueryRow(context.Background(), `SELECT COUNT(*) FROM products WHERE category_id IN (1, 3)`).Scan(&count)
+				require.NoError(t, err)
+				assert.Equal(t, count, res.TotalCount, "TotalCount should match a direct query")
+				assert.Len(t, res.Products, count)
+			},
+		},
 		{
 			name: "Filter by Min Rating (HAVING clause)",
 			options: GetAllOptions{
@@ -165,6 +198,49 @@ func TestProductRepo_GetAll(t *testing.T) {
 				assert.Len(t, res.Products, min(20, countWithMinScore))
 			},
 		},
+		{
+			name: "Facets ignore their own filter",
+			options: GetAllOptions{
+				Filters: FiltersOptions{PriceMin: float64Ptr(50.0), PriceMax: float64Ptr(60.0), CategoryIDs: []int64{1}},
+				Facets:  true,
+			},
+			asserter: func(t *testing.T, res GetAllResult) {
+				require.NotNil(t, res.Facets)
+				var inPriceRange int
+				err := testRepo.DB.QueryRow(context.Background(), `SELECT COUNT(*) FROM products WHERE price BETWEEN 50 AND 60`).Scan(&inPriceRange)
+				require.NoError(t, err)
+				total := 0
+				for _, c := range res.Facets.Categories {
+					var parentID *int64
+					require.NoError(t, testRepo.DB.QueryRow(context.Background(), `SELECT parent_id FROM categories WHERE id = $1`, c.ID).Scan(&parentID))
+					if parentID == nil {
+						total += c.Count
+					}
+				}
+				var uncategorized int
+				err = testRepo.DB.QueryRow(context.Background(), `SELECT COUNT(*) FROM products WHERE price BETWEEN 50 AND 60 AND category_id IS NULL`).Scan(&uncategorized)
+				require.NoError(t, err)
+				assert.Equal(t, inPriceRange-uncategorized, total, "Category counts should ignore the category filter")
+
+				require.Len(t, res.Facets.Prices, len(priceBounds)+1)
+				assert.Nil(t, res.Facets.Prices[len(priceBounds)].Max)
+				total = 0
+				for _, p := range res.Facets.Prices {
+					total += p.Count
+				}
+				var inCategory int
+				err = testRepo.DB.QueryRow(context.Background(), `SELECT COUNT(*) FROM products WHERE category_id = 1`).Scan(&inCategory)
+				require.NoError(t, err)
+				assert.Equal(t, inCategory, total, "Price counts should ignore the price filter")
+
+				require.Len(t, res.Facets.Ratings, 4)
+				assert.Equal(t, 4, res.Facets.Ratings[0].MinScore)
+				for i := 1; i < len(res.Facets.Ratings); i++ {
+					assert.LessOrEqual(t, res.Facets.Ratings[i-1].Count, res.Facets.Ratings[i].Count, "Lower minimums should count more products")
+				}
+				assert.LessOrEqual(t, res.Facets.Ratings[3].Count, res.TotalCount, "Without a rating filter, no rating counts more than the results")
+			},
+		},
 		{
 			name: "Pagination with custom limit",
 			options: GetAllOptions{
@@ -194,6 +270,31 @@ func TestProductRepo_GetAll(t *testing.T) {
 		})
 	}
 
+	t.Run("Category facet counts subcategories", func(t *testing.T) {
+		ctx := context.Background()
+		var parentID, childID int64
+		require.NoError(t, testRepo.DB.QueryRow(ctx, `INSERT INTO categories (name) VALUES ('Facet Test Parent') RETURNING id`).Scan(&parentID))
+		require.NoError(t, testRepo.DB.QueryRow(ctx, `INSERT INTO categories (name, parent_id) VALUES ('Facet Test Child', $1) RETURNING id`, parentID).Scan(&childID))
+		_, err := testRepo.DB.Exec(ctx, `INSERT INTO products (name, price, category_id) VALUES ('Facet Test Product', 987654, $1)`, childID)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			testRepo.DB.Exec(ctx, `DELETE FROM products WHERE name = 'Facet Test Product'`)
+			testRepo.DB.Exec(ctx, `DELETE FROM categories WHERE id IN ($1, $2)`, childID, parentID)
+		})
+
+		res, err := testRepo.GetAll(ctx, GetAllOptions{
+			Filters: FiltersOptions{PriceMin: float64Ptr(987000), CategoryIDs: []int64{childID}},
+			Facets:  true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.TotalCount)
+		counts := map[int64]int{}
+		for _, c := range res.Facets.Categories {
+			counts[c.ID] = c.Count
+		}
+		assert.Equal(t, map[int64]int{parentID: 1, childID: 1}, counts)
+	})
+
 	t.Run("Cursor Pagination", func(t *testing.T) {
 		optsPage1 := GetAllOptions{
 			Sort:       SortOptions{SortBy: "price", Order: "asc"},
@@ -207,6 +308,7 @@ func TestProductRepo_GetAll(t *testing.T) {
 		lastItemPage1 := resPage1.Products[4]
 		cursorPrice := strconv.FormatFloat(lastItemPage1.Price, 'f', -1, 64)
 		cursorID := strconv.FormatInt(lastItemPage1.ID, 10)
+		assert.Equal(t, cursorPrice+","+cursorID, resPage1.NextCursor)
 
 		optsPage2 := GetAllOptions{
 			Sort: SortOptions{SortBy: "price", Order: "asc"},
@@ -234,4 +336,27 @@ func TestProductRepo_GetAll(t *testing.T) {
 			assert.False(t, page1IDs[p.ID], "Product with ID %d from page 1 should not be in page 2", p.ID)
 		}
 	})
+
+	t.Run("NextCursor", func(t *testing.T) {
+		for _, sortBy := range []string{"created_at", "relevance"} {
+			opts := GetAllOptions{
+				Filters:    FiltersOptions{SearchString: stringPtr("headphone")},
+				Sort:       SortOptions{SortBy: sortBy, Order: "desc"},
+				Pagination: PaginationOptions{PageNum: 1},
+			}
+			first, err := testRepo.GetAll(context.Background(), opts)
+			require.NoError(t, err)
+			require.NotEmpty(t, first.NextCursor, sortBy)
+
+			opts.Pagination.Cursor = strings.Split(first.NextCursor, ",")
+			second, err := testRepo.GetAll(context.Background(), opts)
+			require.NoError(t, err)
+			require.Len(t, second.Products, 1, sortBy)
+			assert.NotEqual(t, first.Products[0].ID, second.Products[0].ID, "%s: the cursor skips the first page", sortBy)
+		}
+
+		res, err := testRepo.GetAll(context.Background(), GetAllOptions{Pagination: PaginationOptions{PageNum: 100}})
+		require.NoError(t, err)
+		assert.Empty(t, res.NextCursor, "a page that isn't full is the last one")
+	})
 }
@@ -0,0 +1,61 @@
+package products
+
+import (
+	"context"
+	"ecom/server/types"
+	"fmt"
+	"strings"
+)
+
+// likeEscaper escapes the LIKE wildcards of user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// suggestSQL finds up to $3 names of the given table containing the typed text $2 (as the LIKE
+// pattern $1), or close to a word of them to forgive typos. Names starting with the text ($4)
+// come first, then the closest ones.
+const suggestSQL = `
+	SELECT id, name
+	FROM %s
+	WHERE name ILIKE $1 OR $2 <%% name
+	ORDER BY name ILIKE $4 DESC, word_similarity($2, name) DESC, name
+	LIMIT $3
+`
+
+// Suggest returns the products and categories whose names match what a user has typed so far.
+func (repo *ProductRepo) Suggest(ctx context.Context, q string, limit int) (types.Suggestions, error) {
+	res := types.Suggestions{Products: []types.ProductSuggestion{}, Categories: []types.CategoryRef{}}
+	escaped := likeEscaper.Replace(q)
+	pattern, prefix := "%"+escaped+"%", escaped+"%"
+
+	rows, err := repo.DB.Query(ctx, fmt.Sprintf(suggestSQL, "products"), pattern, q, limit, prefix)
+	if err != nil {
+		return res, fmt.Errorf("failed to query product suggestions: %w", err)
+	}
+	for rows.Next() {
+		var p types.ProductSuggestion
+		if err := rows.Scan(&p.ID, &p.Name); err != nil {
+			rows.Close()
+			return res, fmt.Errorf("failed to scan product suggestion: %w", err)
+		}
+		res.Products = append(res.Products, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, fmt.Errorf("error iterating product suggestions: %w", err)
+	}
+
+	// Fewer categories than products fit a dropdown; they are mostly a shortcut to browse.
+	rows, err = repo.DB.Query(ctx, fmt.Sprintf(suggestSQL, "categories"), pattern, q, max(limit/2, 1), prefix)
+	if err != nil {
+		return res, fmt.Errorf("failed to query category suggestions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c types.CategoryRef
+		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+			return res, fmt.Errorf("failed to scan category suggestion: %w", err)
+		}
+		res.Categories = append(res.Categories, c)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,87 @@
+package products
+
+import (
+	"context"
+	"ecom/server/types"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Create inserts a product with its images and returns its id.
+func (repo *ProductRepo) Create(ctx context.Context, req types.ProductRequest) (int64, error) {
+	var id int64
+	err := repo.inTx(ctx, func(tx pgx.Tx) error {
+		sql := `
+			INSERT INTO products (name, description, price, category_id)
+			VALUES ($1, NULLIF($2, ''), $3, $4)
+			RETURNING id
+		`
+		if err := tx.QueryRow(ctx, sql, strings.TrimSpace(req.Name), req.Description, req.Price, req.CategoryID).Scan(&id); err != nil {
+			return err
+		}
+		return insertImages(ctx, tx, id, req.Images)
+	})
+	return id, err
+}
+
+// Update replaces the fields and images of a product.
+// It returns pgx.ErrNoRows if there is no such product.
+func (repo *ProductRepo) Update(ctx context.Context, productID int64, req types.ProductRequest) error {
+	return repo.inTx(ctx, func(tx pgx.Tx) error {
+		sql := `
+			UPDATE products
+			SET name = $2, description = NULLIF($3, ''), price = $4, category_id = $5, updated_at = NOW()
+			WHERE id = $1
+		`
+		tag, err := tx.Exec(ctx, sql, productID, strings.TrimSpace(req.Name), req.Description, req.Price, req.CategoryID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM product_images WHERE product_id = $1`, productID); err != nil {
+			return fmt.Errorf("failed to delete images: %w", err)
+		}
+		return insertImages(ctx, tx, productID, req.Images)
+	})
+}
+
+// Delete removes a product, with its images, ratings and wishlist and cart entries. Products that
+// were ordered can't be deleted, which fails with a foreign key violation.
+// It returns pgx.ErrNoRows if there is no such product.
+func (repo *ProductRepo) Delete(ctx context.Context, productID int64) error {
+	tag, err := repo.DB.Exec(ctx, `DELETE FROM products WHERE id = $1`, productID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (repo *ProductRepo) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func insertImages(ctx context.Context, tx pgx.Tx, productID int64, images []types.ProductImage) error {
+	for _, img := range images {
+		sql := `INSERT INTO product_images (product_id, url, alt_text) VALUES ($1, $2, NULLIF($3, ''))`
+		if _, err := tx.Exec(ctx, sql, productID, img.URL, img.AltText); err != nil {
+			return fmt.Errorf("failed to insert image: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	"ecom/server/types"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// minSearchesForRate is how often a query must have been searched to be ranked by click-through rate.
+const minSearchesForRate = 5
+
+// RecordSearch logs a search and the number of products it found, under the hash of the token
+// its clicks are sent with. correctedQuery is empty unless the search was run with corrected spelling.
+func (repo *SearchRepo) RecordSearch(ctx context.Context, query, correctedQuery string, resultCount int, tokenHash string) error {
+	sql := `
+		INSERT INTO search_queries (query, corrected_query, result_count, token_hash)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+	`
+	_, err := repo.DB.Exec(ctx, sql, query, correctedQuery, resultCount, tokenHash)
+	return err
+}
+
+// RecordClick logs that a product was opened from the results of the search with the token hash,
+// made after searchedAfter. Clicking it again changes nothing. It returns pgx.ErrNoRows if there
+// is no such search, or it is older.
+func (repo *SearchRepo) RecordClick(ctx context.Context, tokenHash string, productID int64, searchedAfter time.Time) error {
+	sql := `
+		WITH search AS (
+			SELECT id FROM search_queries WHERE token_hash = $1 AND created_at > $3
+		), click AS (
+			INSERT INTO search_clicks (search_id, product_id)
+			SELECT id, $2 FROM search
+			ON CONFLICT DO NOTHING
+		)
+		SELECT EXISTS (SELECT 1 FROM search)
+	`
+	var found bool
+	if err := repo.DB.QueryRow(ctx, sql, tokenHash, productID, searchedAfter).Scan(&found); err != nil {
+		return err
+	}
+	if !found {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// queryStats groups the searches made since $1 by query and returns the first $2 groups in the
+// given order. The conditions apply to single searches, having to their groups.
+func queryStats(conditions, having, orderBy string) string {
+	return fmt.Sprintf(`
+		SELECT query, searches, clicked_searches, last_searched_at
+		FROM (
+			SELECT q.query, COUNT(*) AS searches,
+				COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM search_clicks c WHERE c.search_id = q.id)) AS clicked_searches,
+				MAX(q.created_at) AS last_searched_at
+			FROM search_queries q
+			WHERE q.created_at >= $1 %s
+			GROUP BY q.query
+			%s
+		) s
+		ORDER BY %s, query
+		LIMIT $2
+	`, conditions, having, orderBy)
+}
+
+// SearchAnalytics reports on the searches made since a time, with at most limit queries per report.
+func (repo *SearchRepo) SearchAnalytics(ctx context.Context, since time.Time, limit int) (types.SearchAnalytics, error) {
+	res := types.SearchAnalytics{Since: since}
+	reports := []struct {
+		name  string
+		sql   string
+		stats *[]types.SearchQueryStats
+	}{
+		{"top queries", queryStats("", "", "searches DESC"), &res.TopQueries},
+		{"zero-result queries", queryStats("AND q.result_count = 0", "", "searches DESC"), &res.ZeroResultQueries},
+		{"click-through", queryStats("AND q.result_count > 0", fmt.Sprintf("HAVING COUNT(*) >= %d", minSearchesForRate),
+			"clicked_searches::float8 / searches, searches DESC"), &res.LowestClickThrough},
+	}
+	for _, report := range reports {
+		rows, err := repo.DB.Query(ctx, report.sql, since, limit)
+		if err != nil {
+			return res, fmt.Errorf("failed to query %s: %w", report.name, err)
+		}
+		*report.stats = []types.SearchQueryStats{}
+		for rows.Next() {
+			var s types.SearchQueryStats
+			if err := rows.Scan(&s.Query, &s.Searches, &s.ClickedSearches, &s.LastSearchedAt); err != nil {
+				rows.Close()
+				return res, fmt.Errorf("failed to scan %s row: %w", report.name, err)
+			}
+			s.ClickThroughRate = float64(s.ClickedSearches) / float64(s.Searches)
+			*report.stats = append(*report.stats, s)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return res, fmt.Errorf("error iterating %s rows: %w", report.name, err)
+		}
+	}
+	return res, nil
+}
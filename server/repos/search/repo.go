@@ -0,0 +1,115 @@
+package search
+
+import (
+	"context"
+	"ecom/server/types"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SearchRepo struct {
+	DB *pgx.Conn
+}
+
+func NewSearchRepo(db *pgx.Conn) *SearchRepo {
+	return &SearchRepo{DB: db}
+}
+
+const synonymColumns = `id, terms, created_at, updated_at`
+
+func scanSynonyms(row pgx.Row) (types.SynonymGroup, error) {
+	var g types.SynonymGroup
+	err := row.Scan(&g.ID, &g.Terms, &g.CreatedAt, &g.UpdatedAt)
+	return g, err
+}
+
+func (repo *SearchRepo) ListSynonyms(ctx context.Context) ([]types.SynonymGroup, error) {
+	rows, err := repo.DB.Query(ctx, `SELECT `+synonymColumns+` FROM search_synonyms ORDER BY terms[1]`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query synonyms: %w", err)
+	}
+	defer rows.Close()
+
+	gs := []types.SynonymGroup{}
+	for rows.Next() {
+		g, err := scanSynonyms(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan synonym row: %w", err)
+		}
+		gs = append(gs, g)
+	}
+	return gs, rows.Err()
+}
+
+func (repo *SearchRepo) CreateSynonyms(ctx context.Context, terms []string) (types.SynonymGroup, error) {
+	sql := `INSERT INTO search_synonyms (terms) VALUES ($1) RETURNING ` + synonymColumns
+	return scanSynonyms(repo.DB.QueryRow(ctx, sql, terms))
+}
+
+// UpdateSynonyms replaces the terms of a group. It returns pgx.ErrNoRows if there is no such group.
+func (repo *SearchRepo) UpdateSynonyms(ctx context.Context, groupID int64, terms []string) (types.SynonymGroup, error) {
+	sql := `UPDATE search_synonyms SET terms = $2, updated_at = NOW() WHERE id = $1 RETURNING ` + synonymColumns
+	return scanSynonyms(repo.DB.QueryRow(ctx, sql, groupID, terms))
+}
+
+// DeleteSynonyms removes a group. It returns pgx.ErrNoRows if there is no such group.
+func (repo *SearchRepo) DeleteSynonyms(ctx context.Context, groupID int64) error {
+	tag, err := repo.DB.Exec(ctx, `DELETE FROM search_synonyms WHERE id = $1`, groupID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// FindSynonyms returns the terms of every group containing one of the phrases.
+func (repo *SearchRepo) FindSynonyms(ctx context.Context, phrases []string) ([][]string, error) {
+	rows, err := repo.DB.Query(ctx, `SELECT terms FROM search_synonyms WHERE terms && $1 ORDER BY id`, phrases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query synonyms: %w", err)
+	}
+	defer rows.Close()
+
+	var groups [][]string
+	for rows.Next() {
+		var terms []string
+		if err := rows.Scan(&terms); err != nil {
+			return nil, fmt.Errorf("failed to scan synonym row: %w", err)
+		}
+		groups = append(groups, terms)
+	}
+	return groups, rows.Err()
+}
+
+// SuggestSpellings returns, for each word, the word of the catalog closest to it by trigram
+// similarity, or the word itself if it is known or nothing is close enough. The catalog's words
+// are kept in search_vocabulary by triggers on products and categories.
+func (repo *SearchRepo) SuggestSpellings(ctx context.Context, words []string) ([]string, error) {
+	sql := `
+		SELECT COALESCE(
+			(SELECT v.word FROM search_vocabulary v WHERE v.word = w.input),
+			(SELECT v.word FROM search_vocabulary v WHERE v.word % w.input ORDER BY similarity(v.word, w.input) DESC, v.word LIMIT 1),
+			w.input
+		)
+		FROM unnest($1::text[]) WITH ORDINALITY AS w(input, n)
+		ORDER BY w.n
+	`
+	rows, err := repo.DB.Query(ctx, sql, words)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spellings: %w", err)
+	}
+	defer rows.Close()
+
+	suggested := make([]string, 0, len(words))
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, fmt.Errorf("failed to scan spelling row: %w", err)
+		}
+		suggested = append(suggested, s)
+	}
+	return suggested, rows.Err()
+}
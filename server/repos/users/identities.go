@@ -0,0 +1,78 @@
+package users
+
+import (
+	"context"
+	"ecom/server/types"
+	"fmt"
+	"time"
+)
+
+// CreateOIDCState stores a pending authorization. Expired ones are cleaned up on the way.
+func (repo *UserRepo) CreateOIDCState(ctx context.Context, stateHash, provider, nonce, codeVerifier string, expiresAt time.Time) error {
+	if _, err := repo.DB.Exec(ctx, `DELETE FROM oidc_states WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("failed to delete expired states: %w", err)
+	}
+	sql := `
+		INSERT INTO oidc_states (state_hash, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := repo.DB.Exec(ctx, sql, stateHash, provider, nonce, codeVerifier, expiresAt)
+	return err
+}
+
+// ConsumeOIDCState deletes a pending authorization and returns its nonce and PKCE verifier.
+// It returns pgx.ErrNoRows if there is no such unexpired state for the provider.
+func (repo *UserRepo) ConsumeOIDCState(ctx context.Context, stateHash, provider string) (nonce, codeVerifier string, err error) {
+	sql := `
+		DELETE FROM oidc_states
+		WHERE state_hash = $1 AND provider = $2 AND expires_at > NOW()
+		RETURNING nonce, code_verifier
+	`
+	err = repo.DB.QueryRow(ctx, sql, stateHash, provider).Scan(&nonce, &codeVerifier)
+	return nonce, codeVerifier, err
+}
+
+// GetByIdentity returns the user linked to a provider account.
+// It returns pgx.ErrNoRows if the account isn't linked.
+func (repo *UserRepo) GetByIdentity(ctx context.Context, provider, subject string) (types.User, error) {
+	sql := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = (SELECT user_id FROM identities WHERE provider = $1 AND subject = $2)
+	`
+	return scanUser(repo.DB.QueryRow(ctx, sql, provider, subject))
+}
+
+// LinkIdentity links a provider account to a user.
+func (repo *UserRepo) LinkIdentity(ctx context.Context, userID int64, provider, subject, email string) error {
+	sql := `
+		INSERT INTO identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+	`
+	_, err := repo.DB.Exec(ctx, sql, userID, provider, subject, email)
+	return err
+}
+
+// CreateWithIdentity inserts a user without a password and links the provider account to it,
+// in one transaction.
+func (repo *UserRepo) CreateWithIdentity(ctx context.Context, email, name string, emailVerified bool, provider, subject string) (types.User, error) {
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return types.User{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `
+		INSERT INTO users (email, pass, name, email_verified_at)
+		VALUES ($1, '', NULLIF($2, ''), CASE WHEN $3 THEN NOW() END)
+		RETURNING ` + userColumns
+	u, err := scanUser(tx.QueryRow(ctx, sql, email, name, emailVerified))
+	if err != nil {
+		return types.User{}, err
+	}
+	sql = `INSERT INTO identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(ctx, sql, u.ID, provider, subject, email); err != nil {
+		return types.User{}, err
+	}
+	return u, tx.Commit(ctx)
+}
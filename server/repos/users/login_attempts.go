@@ -0,0 +1,66 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Scopes of the failed login counters. The magic link scopes count links requested
+// instead of failures, with the same locking.
+const (
+	AttemptScopeAccount        = "account"
+	AttemptScopeIP             = "ip"
+	AttemptScopeMagicLinkEmail = "link_email"
+	AttemptScopeMagicLinkIP    = "link_ip"
+)
+
+// GetLockedUntil returns when the lock of a counter ends, or the zero time if it isn't locked.
+func (repo *UserRepo) GetLockedUntil(ctx context.Context, scope, key string) (time.Time, error) {
+	var until *time.Time
+	sql := `SELECT locked_until FROM login_attempts WHERE scope = $1 AND key = $2`
+	err := repo.DB.QueryRow(ctx, sql, scope, key).Scan(&until)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	if until == nil {
+		return time.Time{}, nil
+	}
+	return *until, nil
+}
+
+// RecordLoginFailure counts a failed login and returns the number of failures so far.
+// The count starts over when the previous failure is older than window.
+func (repo *UserRepo) RecordLoginFailure(ctx context.Context, scope, key string, window time.Duration) (int, error) {
+	var failures int
+	sql := `
+		INSERT INTO login_attempts (scope, key, failures, last_failure_at)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < NOW() - $3::interval THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = NOW()
+		RETURNING failures
+	`
+	err := repo.DB.QueryRow(ctx, sql, scope, key, window).Scan(&failures)
+	return failures, err
+}
+
+func (repo *UserRepo) LockLogin(ctx context.Context, scope, key string, until time.Time) error {
+	sql := `UPDATE login_attempts SET locked_until = $3 WHERE scope = $1 AND key = $2`
+	_, err := repo.DB.Exec(ctx, sql, scope, key, until)
+	return err
+}
+
+// ClearLoginFailures resets a counter and lifts its lock.
+func (repo *UserRepo) ClearLoginFailures(ctx context.Context, scope, key string) error {
+	_, err := repo.DB.Exec(ctx, `DELETE FROM login_attempts WHERE scope = $1 AND key = $2`, scope, key)
+	return err
+}
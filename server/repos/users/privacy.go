@@ -0,0 +1,197 @@
+package users
+
+import (
+	"context"
+	"ecom/server/repos/addresses"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrOpenOrders is returned when deleting a user whose orders are still in progress.
+var ErrOpenOrders = errors.New("user has orders in progress")
+
+// collect runs a query and scans every row of it with scan.
+func collect[T any](ctx context.Context, tx pgx.Tx, sql string, scan func(pgx.Rows) (T, error), args ...any) ([]T, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ts := []T{}
+	for rows.Next() {
+		t, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, rows.Err()
+}
+
+// Export reads the data of a user kept outside of their sessions, from one snapshot of the database.
+// It returns pgx.ErrNoRows if there is no such user.
+func (repo *UserRepo) Export(ctx context.Context, userID int64) (types.AccountExport, error) {
+	var ex types.AccountExport
+	tx, err := repo.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return ex, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ex.Profile, err = scanUser(tx.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, userID))
+	if err != nil {
+		return ex, err
+	}
+
+	ex.Addresses, err = collect(ctx, tx, `
+		SELECT id, line1, city, COALESCE(state, ''), COALESCE(zip_code, ''), country, is_default
+		FROM addresses WHERE user_id = $1 ORDER BY id
+	`, func(rows pgx.Rows) (a types.Address, err error) {
+		err = rows.Scan(&a.ID, &a.Line1, &a.City, &a.State, &a.ZipCode, &a.Country, &a.IsDefault)
+		return a, err
+	}, userID)
+	if err != nil {
+		return ex, fmt.Errorf("failed to read addresses: %w", err)
+	}
+
+	ex.Orders, err = collect(ctx, tx, `
+		SELECT o.id, o.status, o.total_amount, o.payment_method, o.address_id,
+			COALESCE((
+				SELECT json_agg(json_build_object(
+					'product_id', oi.product_id, 'product_name', p.name,
+					'quantity', oi.quantity, 'price', oi.price) ORDER BY oi.id)
+				FROM order_items oi JOIN products p ON p.id = oi.product_id
+				WHERE oi.order_id = o.id
+			), '[]'),
+			o.created_at, o.updated_at
+		FROM orders o WHERE o.user_id = $1 ORDER BY o.id
+	`, func(rows pgx.Rows) (o types.Order, err error) {
+		err = rows.Scan(&o.ID, &o.Status, &o.TotalAmount, &o.PaymentMethod, &o.ShippingAddressID, &o.Items, &o.CreatedAt, &o.UpdatedAt)
+		return o, err
+	}, userID)
+	if err != nil {
+		return ex, fmt.Errorf("failed to read orders: %w", err)
+	}
+
+	ex.Ratings, err = collect(ctx, tx, `
+		SELECT r.product_id, p.name, r.score, COALESCE(r.review, ''), r.created_at
+		FROM ratings r JOIN products p ON p.id = r.product_id
+		WHERE r.user_id = $1 ORDER BY r.created_at
+	`, func(rows pgx.Rows) (r types.Rating, err error) {
+		err = rows.Scan(&r.ProductID, &r.ProductName, &r.Score, &r.Review, &r.CreatedAt)
+		return r, err
+	}, userID)
+	if err != nil {
+		return ex, fmt.Errorf("failed to read ratings: %w", err)
+	}
+
+	ex.Wishlist, err = collect(ctx, tx, `
+		SELECT w.product_id, p.name, w.created_at
+		FROM wishlists w JOIN products p ON p.id = w.product_id
+		WHERE w.user_id = $1 ORDER BY w.created_at
+	`, func(rows pgx.Rows) (w types.WishlistItem, err error) {
+		err = rows.Scan(&w.ProductID, &w.ProductName, &w.AddedAt)
+		return w, err
+	}, userID)
+	if err != nil {
+		return ex, fmt.Errorf("failed to read wishlist: %w", err)
+	}
+
+	ex.Cart, err = collect(ctx, tx, `
+		SELECT ci.product_id, p.name, ci.quantity
+		FROM carts c
+		JOIN cart_items ci ON ci.cart_id = c.id
+		JOIN products p ON p.id = ci.product_id
+		WHERE c.user_id = $1 ORDER BY c.id, ci.product_id
+	`, func(rows pgx.Rows) (c types.CartItem, err error) {
+		err = rows.Scan(&c.ProductID, &c.ProductName, &c.Quantity)
+		return c, err
+	}, userID)
+	if err != nil {
+		return ex, fmt.Errorf("failed to read cart: %w", err)
+	}
+
+	ex.LinkedAccounts, err = collect(ctx, tx, `
+		SELECT provider, COALESCE(email, ''), created_at
+		FROM identities WHERE user_id = $1 ORDER BY id
+	`, func(rows pgx.Rows) (l types.LinkedAccount, err error) {
+		err = rows.Scan(&l.Provider, &l.Email, &l.LinkedAt)
+		return l, err
+	}, userID)
+	if err != nil {
+		return ex, fmt.Errorf("failed to read linked accounts: %w", err)
+	}
+	return ex, nil
+}
+
+// Anonymize consumes an account deletion token and deletes the personal data of its user in one
+// transaction. The user row stays, stripped of anything identifying, so their orders and ratings
+// keep counting; everything else is deleted. It returns pgx.ErrNoRows if the token is invalid or
+// its user is gone, and ErrOpenOrders while one of their orders is still in progress, in which
+// case the token stays usable.
+func (repo *UserRepo) Anonymize(ctx context.Context, tokenHash string) error {
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	err = tx.QueryRow(ctx, `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash, TokenAccountDeletion).Scan(&userID)
+	if err != nil {
+		return err
+	}
+
+	var email string
+	if err := tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, userID).Scan(&email); err != nil {
+		return err
+	}
+
+	var open bool
+	sql := `SELECT EXISTS (SELECT 1 FROM orders WHERE user_id = $1 AND status <> ALL($2))`
+	if err := tx.QueryRow(ctx, sql, userID, addresses.ClosedOrderStatuses).Scan(&open); err != nil {
+		return fmt.Errorf("failed to check orders: %w", err)
+	}
+	if open {
+		return ErrOpenOrders
+	}
+
+	// Deleting addresses detaches them from past orders, which keep their amounts and items.
+	for _, table := range []string{"addresses", "wishlists", "carts", "identities", "api_keys", "refresh_tokens", "user_tokens", "totp_recovery_codes"} {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM login_attempts WHERE scope = $1 AND key = LOWER($2)`, AttemptScopeAccount, email); err != nil {
+		return fmt.Errorf("failed to delete login attempts: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE ratings SET review = NULL WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear reviews: %w", err)
+	}
+
+	sql = `
+		UPDATE users SET
+			email = 'deleted-' || id || '@deleted.invalid',
+			pass = '',
+			name = NULL,
+			phone = NULL,
+			email_verified_at = NULL,
+			totp_secret = NULL,
+			totp_enabled_at = NULL,
+			totp_last_step = NULL,
+			deleted_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, sql, userID); err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	return tx.Commit(ctx)
+}
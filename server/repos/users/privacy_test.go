@@ -0,0 +1,95 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRepo *UserRepo
+
+// TestMain sets up the db connection for all repo tests.
+func TestMain(m *testing.M) {
+	if err := godotenv.Load("../../../.env"); err != nil {
+		log.Println("Could not load .env file, will rely on environment variables.")
+	}
+
+	dburl := os.Getenv("DB_URL")
+	if dburl == "" {
+		log.Fatal("DB_URL is not set. Please provide it via .env file or environment variable.")
+	}
+
+	db, err := pgx.Connect(context.Background(), dburl)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	testRepo = NewUserRepo(db)
+
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func TestUserRepo_ExportAndAnonymize(t *testing.T) {
+	ctx := context.Background()
+	db := testRepo.DB
+
+	u, err := testRepo.Create(ctx, fmt.Sprintf("privacy-test-%d@example.com", time.Now().UnixNano()), "hash")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Exec(ctx, `DELETE FROM users WHERE id = $1`, u.ID) })
+
+	var addressID, productID int64
+	require.NoError(t, db.QueryRow(ctx, `
+		INSERT INTO addresses (user_id, line1, city, country, is_default)
+		VALUES ($1, '1 Main St', 'Springfield', 'US', TRUE) RETURNING id
+	`, u.ID).Scan(&addressID))
+	require.NoError(t, db.QueryRow(ctx, `SELECT id FROM products ORDER BY id LIMIT 1`).Scan(&productID))
+	_, err = db.Exec(ctx, `INSERT INTO wishlists (user_id, product_id) VALUES ($1, $2)`, u.ID, productID)
+	require.NoError(t, err)
+	var orderID int64
+	require.NoError(t, db.QueryRow(ctx, `
+		INSERT INTO orders (user_id, address_id, status, total_amount, payment_method)
+		VALUES ($1, $2, 'processing', 10, 'card') RETURNING id
+	`, u.ID, addressID).Scan(&orderID))
+
+	ex, err := testRepo.Export(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, u.Email, ex.Profile.Email)
+	assert.Len(t, ex.Addresses, 1)
+	assert.Len(t, ex.Wishlist, 1)
+	require.Len(t, ex.Orders, 1)
+	assert.Equal(t, &addressID, ex.Orders[0].ShippingAddressID)
+	assert.Empty(t, ex.Cart)
+
+	require.NoError(t, testRepo.CreateUserToken(ctx, u.ID, TokenAccountDeletion, "privacy-test-hash", "", time.Now().Add(time.Hour)))
+
+	assert.ErrorIs(t, testRepo.Anonymize(ctx, "privacy-test-hash"), ErrOpenOrders)
+	_, err = db.Exec(ctx, `UPDATE orders SET status = 'completed' WHERE id = $1`, orderID)
+	require.NoError(t, err)
+	require.NoError(t, testRepo.Anonymize(ctx, "privacy-test-hash"), "the token survives a refused deletion")
+
+	deleted, err := testRepo.Get(ctx, u.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+	assert.Equal(t, fmt.Sprintf("deleted-%d@deleted.invalid", u.ID), deleted.Email)
+	assert.Empty(t, deleted.Pass)
+
+	ex, err = testRepo.Export(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Empty(t, ex.Addresses)
+	assert.Empty(t, ex.Wishlist)
+	require.Len(t, ex.Orders, 1, "orders are kept")
+	assert.Nil(t, ex.Orders[0].ShippingAddressID)
+
+	assert.ErrorIs(t, testRepo.Anonymize(ctx, "privacy-test-hash"), pgx.ErrNoRows)
+}
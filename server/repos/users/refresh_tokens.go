@@ -0,0 +1,167 @@
+package users
+
+import (
+	"context"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrAlreadyRevoked is returned when rotating a refresh token that was revoked concurrently.
+var ErrAlreadyRevoked = errors.New("refresh token already revoked")
+
+// RefreshToken is a stored refresh token. Tokens issued from one login share a FamilyID,
+// which makes a family the session a user sees.
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	FamilyID  string
+	MFA       bool   // The session was started with a second factor.
+	UserAgent string // Of the client the token was issued to.
+	IP        string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// CreateRefreshToken stores t under the token hash and returns it with its ID and CreatedAt set.
+func (repo *UserRepo) CreateRefreshToken(ctx context.Context, t RefreshToken, tokenHash string) (RefreshToken, error) {
+	sql := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, mfa, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err := repo.DB.QueryRow(ctx, sql, t.UserID, t.FamilyID, tokenHash, t.MFA, t.UserAgent, t.IP, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+	return t, err
+}
+
+func (repo *UserRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	var t RefreshToken
+	sql := `
+		SELECT id, user_id, family_id, mfa, expires_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+	err := repo.DB.QueryRow(ctx, sql, tokenHash).Scan(&t.ID, &t.UserID, &t.FamilyID, &t.MFA, &t.ExpiresAt, &t.RevokedAt, &t.CreatedAt)
+	return t, err
+}
+
+// RotateRefreshToken revokes the token with oldID and stores its replacement in the same family,
+// in one transaction. Only the ExpiresAt, UserAgent and IP of next are used.
+// It returns ErrAlreadyRevoked if the old token was no longer active.
+func (repo *UserRepo) RotateRefreshToken(ctx context.Context, oldID int64, next RefreshToken, newHash string) (RefreshToken, error) {
+	t := RefreshToken{ExpiresAt: next.ExpiresAt, UserAgent: next.UserAgent, IP: next.IP}
+
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return t, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING user_id, family_id, mfa
+	`, oldID).Scan(&t.UserID, &t.FamilyID, &t.MFA)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return t, ErrAlreadyRevoked
+		}
+		return t, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, mfa, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, t.UserID, t.FamilyID, newHash, t.MFA, t.UserAgent, t.IP, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return t, fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET replaced_by = $1 WHERE id = $2`, t.ID, oldID); err != nil {
+		return t, fmt.Errorf("failed to link refresh tokens: %w", err)
+	}
+
+	return t, tx.Commit(ctx)
+}
+
+// RevokeRefreshTokenFamily revokes every still active token of a family.
+func (repo *UserRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	sql := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := repo.DB.Exec(ctx, sql, familyID)
+	return err
+}
+
+// RevokeUserRefreshTokens revokes every still active token of a user, ending all their sessions.
+func (repo *UserRepo) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	sql := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := repo.DB.Exec(ctx, sql, userID)
+	return err
+}
+
+// TouchSession reports whether a token family still has an active token, i.e. whether the
+// session was neither ended nor expired, and records that the session was used. The use is
+// only written once a minute so a busy client doesn't update the token on every request.
+func (repo *UserRepo) TouchSession(ctx context.Context, familyID string) (bool, error) {
+	var ok bool
+	sql := `
+		WITH active AS (
+			SELECT id, last_used_at
+			FROM refresh_tokens
+			WHERE family_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		), touched AS (
+			UPDATE refresh_tokens rt SET last_used_at = NOW()
+			FROM active a
+			WHERE rt.id = a.id AND (a.last_used_at IS NULL OR a.last_used_at < NOW() - INTERVAL '1 minute')
+		)
+		SELECT EXISTS (SELECT 1 FROM active)
+	`
+	err := repo.DB.QueryRow(ctx, sql, familyID).Scan(&ok)
+	return ok, err
+}
+
+// ListSessions returns the active sessions of a user, most recently used first. A session is
+// the active token of a family; it was last used by its latest access token or refresh.
+func (repo *UserRepo) ListSessions(ctx context.Context, userID int64) ([]types.Session, error) {
+	sql := `
+		SELECT rt.family_id, COALESCE(rt.user_agent, ''), COALESCE(rt.ip, ''), rt.mfa,
+			(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = rt.family_id),
+			COALESCE(rt.last_used_at, rt.created_at) AS last_used_at, rt.expires_at
+		FROM refresh_tokens rt
+		WHERE rt.user_id = $1 AND rt.revoked_at IS NULL AND rt.expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`
+	rows, err := repo.DB.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	ss := []types.Session{}
+	for rows.Next() {
+		var s types.Session
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.MFA, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session row: %w", err)
+		}
+		ss = append(ss, s)
+	}
+	return ss, rows.Err()
+}
+
+// RevokeSession revokes the token family of a user's session.
+// It returns pgx.ErrNoRows if the user has no such active session.
+func (repo *UserRepo) RevokeSession(ctx context.Context, userID int64, familyID string) error {
+	sql := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL`
+	tag, err := repo.DB.Exec(ctx, sql, userID, familyID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
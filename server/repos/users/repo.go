@@ -0,0 +1,136 @@
+package users
+
+import (
+	"context"
+	"ecom/server/types"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type UserRepo struct {
+	DB *pgx.Conn
+}
+
+func NewUserRepo(db *pgx.Conn) *UserRepo {
+	return &UserRepo{DB: db}
+}
+
+// userColumns is the select list read by scanUser.
+const userColumns = `id, email, pass, role, COALESCE(name, ''), COALESCE(phone, ''), email_verified_at IS NOT NULL, totp_enabled_at IS NOT NULL, service_account, created_at, deleted_at`
+
+func scanUser(row pgx.Row) (types.User, error) {
+	var u types.User
+	err := row.Scan(&u.ID, &u.Email, &u.Pass, &u.Role, &u.Name, &u.Phone, &u.EmailVerified, &u.TOTPEnabled, &u.ServiceAccount, &u.CreatedAt, &u.DeletedAt)
+	return u, err
+}
+
+// Create inserts a new user with the given password hash. The role falls back to the column default.
+func (repo *UserRepo) Create(ctx context.Context, email, passHash string) (types.User, error) {
+	sql := `
+		INSERT INTO users (email, pass)
+		VALUES ($1, $2)
+		RETURNING ` + userColumns
+	return scanUser(repo.DB.QueryRow(ctx, sql, email, passHash))
+}
+
+// CreateServiceAccount inserts a user without a password, which can only use API keys.
+func (repo *UserRepo) CreateServiceAccount(ctx context.Context, email, role string) (types.User, error) {
+	sql := `
+		INSERT INTO users (email, pass, role, service_account, email_verified_at)
+		VALUES ($1, '', $2, TRUE, NOW())
+		RETURNING ` + userColumns
+	return scanUser(repo.DB.QueryRow(ctx, sql, email, role))
+}
+
+func (repo *UserRepo) Get(ctx context.Context, userID int64) (types.User, error) {
+	sql := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return scanUser(repo.DB.QueryRow(ctx, sql, userID))
+}
+
+// GetByEmail looks a user up by email, ignoring case.
+func (repo *UserRepo) GetByEmail(ctx context.Context, email string) (types.User, error) {
+	sql := `SELECT ` + userColumns + ` FROM users WHERE LOWER(email) = LOWER($1)`
+	return scanUser(repo.DB.QueryRow(ctx, sql, email))
+}
+
+// List returns up to limit users with an id greater than afterID, ordered by id.
+func (repo *UserRepo) List(ctx context.Context, afterID int64, limit int) ([]types.User, error) {
+	sql := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+	rows, err := repo.DB.Query(ctx, sql, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	us := make([]types.User, 0, limit)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		us = append(us, u)
+	}
+	return us, rows.Err()
+}
+
+// UpdatePassword replaces the stored password hash of a user.
+func (repo *UserRepo) UpdatePassword(ctx context.Context, userID int64, passHash string) error {
+	_, err := repo.DB.Exec(ctx, `UPDATE users SET pass = $2 WHERE id = $1`, userID, passHash)
+	return err
+}
+
+// UpdateProfile sets the non-nil profile fields. An empty string clears a field.
+func (repo *UserRepo) UpdateProfile(ctx context.Context, userID int64, name, phone *string) (types.User, error) {
+	sql := `
+		UPDATE users SET
+			name = CASE WHEN $2::text IS NULL THEN name ELSE NULLIF($2, '') END,
+			phone = CASE WHEN $3::text IS NULL THEN phone ELSE NULLIF($3, '') END
+		WHERE id = $1
+		RETURNING ` + userColumns
+	return scanUser(repo.DB.QueryRow(ctx, sql, userID, name, phone))
+}
+
+// UpdateEmail sets a new, already confirmed, email address.
+// ChangeEmail consumes an email change token and makes the address it was issued for the
+// user's verified email, in one transaction: if the update fails, the token stays usable.
+// It returns pgx.ErrNoRows if there is no such token.
+func (repo *UserRepo) ChangeEmail(ctx context.Context, tokenHash string) error {
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	var email string
+	err = tx.QueryRow(ctx, `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, COALESCE(payload, '')
+	`, tokenHash, TokenEmailChange).Scan(&userID, &email)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET email = $2, email_verified_at = NOW() WHERE id = $1`, userID, email)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
+}
+
+func (repo *UserRepo) MarkEmailVerified(ctx context.Context, userID int64) error {
+	sql := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`
+	_, err := repo.DB.Exec(ctx, sql, userID)
+	return err
+}
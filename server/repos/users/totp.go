@@ -0,0 +1,123 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TOTPState is the two-factor configuration of a user.
+type TOTPState struct {
+	Secret   string // Empty if the user never enrolled.
+	Enabled  bool
+	LastStep int64
+}
+
+func (repo *UserRepo) GetTOTP(ctx context.Context, userID int64) (TOTPState, error) {
+	var st TOTPState
+	sql := `
+		SELECT COALESCE(totp_secret, ''), totp_enabled_at IS NOT NULL, COALESCE(totp_last_step, 0)
+		FROM users
+		WHERE id = $1
+	`
+	err := repo.DB.QueryRow(ctx, sql, userID).Scan(&st.Secret, &st.Enabled, &st.LastStep)
+	return st, err
+}
+
+// SetTOTPSecret stores the secret of a pending enrollment.
+func (repo *UserRepo) SetTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	sql := `UPDATE users SET totp_secret = $2, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1`
+	_, err := repo.DB.Exec(ctx, sql, userID, secret)
+	return err
+}
+
+// EnableTOTP completes an enrollment and stores its recovery codes, in one transaction.
+func (repo *UserRepo) EnableTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error {
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2 WHERE id = $1`
+	if _, err := tx.Exec(ctx, sql, userID, step); err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep records an accepted time step. It returns false if the step, or a later one,
+// was already used: the code is being replayed.
+func (repo *UserRepo) UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	sql := `
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`
+	tag, err := repo.DB.Exec(ctx, sql, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (repo *UserRepo) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	sql := `
+		INSERT INTO totp_recovery_codes (user_id, code_hash)
+		SELECT $1, UNNEST($2::text[])
+	`
+	if _, err := tx.Exec(ctx, sql, userID, codeHashes); err != nil {
+		return fmt.Errorf("failed to insert recovery codes: %w", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It returns false if there is none.
+func (repo *UserRepo) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	sql := `
+		UPDATE totp_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	tag, err := repo.DB.Exec(ctx, sql, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DisableTOTP removes the secret and the recovery codes of a user.
+func (repo *UserRepo) DisableTOTP(ctx context.Context, userID int64) error {
+	tx, err := repo.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1`
+	if _, err := tx.Exec(ctx, sql, userID); err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	return tx.Commit(ctx)
+}
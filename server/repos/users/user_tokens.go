@@ -0,0 +1,59 @@
+package users
+
+import (
+	"context"
+	"time"
+)
+
+// Purposes of the single-use tokens stored in user_tokens.
+const (
+	TokenAccountDeletion = "account_deletion"
+	TokenEmailChange     = "email_change"
+	TokenEmailVerify     = "email_verify"
+	TokenMagicLink       = "magic_link"
+	TokenPasswordReset   = "password_reset"
+)
+
+// CreateUserToken stores the hash of a single-use token.
+func (repo *UserRepo) CreateUserToken(ctx context.Context, userID int64, purpose, tokenHash, payload string, expiresAt time.Time) error {
+	sql := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, payload, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := repo.DB.Exec(ctx, sql, userID, purpose, tokenHash, payload, expiresAt)
+	return err
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns its owner and payload.
+// It returns pgx.ErrNoRows if there is no such token.
+func (repo *UserRepo) ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (userID int64, payload string, err error) {
+	sql := `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, COALESCE(payload, '')
+	`
+	err = repo.DB.QueryRow(ctx, sql, tokenHash, purpose).Scan(&userID, &payload)
+	return userID, payload, err
+}
+
+// InvalidateUserTokens marks every unused token of a user for the purpose as used.
+func (repo *UserRepo) InvalidateUserTokens(ctx context.Context, userID int64, purpose string) error {
+	sql := `UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	_, err := repo.DB.Exec(ctx, sql, userID, purpose)
+	return err
+}
+
+// GetActiveUserTokenPayload returns the payload of the newest usable token of a user for the purpose.
+// It returns pgx.ErrNoRows if there is none.
+func (repo *UserRepo) GetActiveUserTokenPayload(ctx context.Context, userID int64, purpose string) (string, error) {
+	var payload string
+	sql := `
+		SELECT COALESCE(payload, '')
+		FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	err := repo.DB.QueryRow(ctx, sql, userID, purpose).Scan(&payload)
+	return payload, err
+}
@@ -0,0 +1,117 @@
+package addresses
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/repos"
+	repoAddresses "ecom/server/repos/addresses"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const pgUniqueViolation = "23505"
+
+// AddressService manages the address book of the caller.
+type AddressService struct {
+	Repo repos.IAddressRepo
+}
+
+func NewService(repo repos.IAddressRepo) *AddressService {
+	return &AddressService{Repo: repo}
+}
+
+func callerID(ctx context.Context) (int64, error) {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return 0, customErrors.Unauthorized
+	}
+	return p.UserID, nil
+}
+
+func toAddress(req types.AddressRequest) types.Address {
+	return types.Address{
+		Line1:     req.Line1,
+		City:      req.City,
+		State:     req.State,
+		ZipCode:   req.ZipCode,
+		Country:   req.Country,
+		IsDefault: req.IsDefault,
+	}
+}
+
+func (svc *AddressService) List(ctx context.Context) ([]types.Address, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	as, err := svc.Repo.List(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	return as, nil
+}
+
+// Create adds an address. The first address of a user always becomes the default.
+func (svc *AddressService) Create(ctx context.Context, req types.AddressRequest) (types.Address, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return types.Address{}, err
+	}
+	a, err := svc.Repo.Create(ctx, userID, toAddress(req))
+	if err != nil {
+		if isDefaultConflict(err) {
+			return types.Address{}, errDefaultConflict
+		}
+		return types.Address{}, fmt.Errorf("failed to create address: %w", err)
+	}
+	return a, nil
+}
+
+func (svc *AddressService) Update(ctx context.Context, addressID int64, req types.AddressRequest) (types.Address, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return types.Address{}, err
+	}
+	a, err := svc.Repo.Update(ctx, userID, addressID, toAddress(req))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.Address{}, customErrors.NotFound
+		}
+		if isDefaultConflict(err) {
+			return types.Address{}, errDefaultConflict
+		}
+		return types.Address{}, fmt.Errorf("failed to update address: %w", err)
+	}
+	return a, nil
+}
+
+// Delete removes an address, unless an order in progress still ships to it.
+func (svc *AddressService) Delete(ctx context.Context, addressID int64) error {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := svc.Repo.Delete(ctx, userID, addressID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		if errors.Is(err, repoAddresses.ErrInUse) {
+			return fmt.Errorf("%w: %w", customErrors.Conflict, err)
+		}
+		return fmt.Errorf("failed to delete address: %w", err)
+	}
+	return nil
+}
+
+var errDefaultConflict = fmt.Errorf("%w: the default address was changed at the same time, please try again", customErrors.Conflict)
+
+// isDefaultConflict reports whether a concurrent change claimed the default address first.
+func isDefaultConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation && pgErr.ConstraintName == "addresses_one_default_idx"
+}
@@ -0,0 +1,55 @@
+package addresses
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/repos"
+	repoAddresses "ecom/server/repos/addresses"
+	"ecom/server/types"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingRepo fails every change to the address book with err.
+type failingRepo struct {
+	repos.IAddressRepo
+	err error
+}
+
+func (r *failingRepo) Create(context.Context, int64, types.Address) (types.Address, error) {
+	return types.Address{}, r.err
+}
+
+func (r *failingRepo) Update(context.Context, int64, int64, types.Address) (types.Address, error) {
+	return types.Address{}, r.err
+}
+
+func (r *failingRepo) Delete(context.Context, int64, int64) error {
+	return r.err
+}
+
+func TestAddressErrors(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1})
+	req := types.AddressRequest{Line1: "1 Main St", City: "Springfield", Country: "US"}
+
+	svc := NewService(&failingRepo{err: &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "addresses_one_default_idx"}})
+	_, err := svc.Create(ctx, req)
+	assert.ErrorIs(t, err, customErrors.Conflict, "a concurrent change claimed the default")
+	_, err = svc.Update(ctx, 1, req)
+	assert.ErrorIs(t, err, customErrors.Conflict)
+
+	svc = NewService(&failingRepo{err: pgx.ErrNoRows})
+	_, err = svc.Update(ctx, 1, req)
+	assert.ErrorIs(t, err, customErrors.NotFound)
+	assert.ErrorIs(t, svc.Delete(ctx, 1), customErrors.NotFound)
+
+	svc = NewService(&failingRepo{err: repoAddresses.ErrInUse})
+	assert.ErrorIs(t, svc.Delete(ctx, 1), customErrors.Conflict)
+
+	_, err = svc.Create(context.Background(), req)
+	assert.ErrorIs(t, err, customErrors.Unauthorized, "there is no caller")
+}
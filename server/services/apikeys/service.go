@@ -0,0 +1,158 @@
+package apikeys
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/repos"
+	"ecom/server/services/authz"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// serviceAccountDomain makes up the email of service accounts. The .invalid TLD can't
+// receive mail, so no login link or password reset can ever reach one.
+const serviceAccountDomain = "@svc.invalid"
+
+var serviceAccountName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type APIKeyService struct {
+	Repo        repos.IAPIKeyRepo
+	Users       repos.IUserRepo
+	Permissions repos.IPermissionRepo
+	Policy      authz.Authorizer
+}
+
+func NewService(repo repos.IAPIKeyRepo, users repos.IUserRepo, permissions repos.IPermissionRepo, policy authz.Authorizer) *APIKeyService {
+	return &APIKeyService{Repo: repo, Users: users, Permissions: permissions, Policy: policy}
+}
+
+// Authenticate verifies an API key and returns its owner, limited to the key's scopes.
+func (svc *APIKeyService) Authenticate(ctx context.Context, key string) (auth.Principal, error) {
+	prefix, secret, ok := auth.ParseAPIKey(key)
+	if !ok {
+		return auth.Principal{}, customErrors.Unauthorized
+	}
+	sk, err := svc.Repo.GetActiveByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return auth.Principal{}, customErrors.Unauthorized
+		}
+		return auth.Principal{}, fmt.Errorf("failed to get api key: %w", err)
+	}
+	if !auth.VerifyAPIKeySecret(secret, sk.Hash) {
+		return auth.Principal{}, customErrors.Unauthorized
+	}
+
+	// Usage tracking is best effort; it must not fail the request.
+	if err := svc.Repo.Touch(ctx, sk.ID); err != nil {
+		log.Printf("failed to record use of api key %d: %v", sk.ID, err)
+	}
+	return auth.Principal{UserID: sk.UserID, Role: sk.Role, APIKeyID: sk.ID, Scopes: sk.Scopes}, nil
+}
+
+// Create issues a key for a user. Every scope must be held both by the caller, so keys
+// can't be used to escalate privileges, and by the role of the key's owner.
+func (svc *APIKeyService) Create(ctx context.Context, req types.CreateAPIKeyRequest) (types.CreatedAPIKey, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return types.CreatedAPIKey{}, fmt.Errorf("%w: expires_at must be in the future", customErrors.InvalidInput)
+	}
+	owner, err := svc.Users.Get(ctx, req.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.CreatedAPIKey{}, fmt.Errorf("%w: user %d", customErrors.NotFound, req.UserID)
+		}
+		return types.CreatedAPIKey{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	scopes := slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+	for _, scope := range scopes {
+		if err := svc.Policy.Authorize(ctx, scope); err != nil {
+			if errors.Is(err, customErrors.Forbidden) {
+				return types.CreatedAPIKey{}, fmt.Errorf("%w: you don't hold %q", customErrors.Forbidden, scope)
+			}
+			return types.CreatedAPIKey{}, err
+		}
+		ok, err := svc.Permissions.HasPermission(ctx, owner.Role, scope)
+		if err != nil {
+			return types.CreatedAPIKey{}, fmt.Errorf("failed to check permission: %w", err)
+		}
+		if !ok {
+			return types.CreatedAPIKey{}, fmt.Errorf("%w: role %q of the user doesn't hold %q", customErrors.InvalidInput, owner.Role, scope)
+		}
+	}
+
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return types.CreatedAPIKey{}, err
+	}
+	k, err := svc.Repo.Create(ctx, types.APIKey{
+		UserID:    owner.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}, hash)
+	if err != nil {
+		return types.CreatedAPIKey{}, fmt.Errorf("failed to create api key: %w", err)
+	}
+	return types.CreatedAPIKey{APIKey: k, Key: key}, nil
+}
+
+// List returns the keys of a user, or every key if userID is 0.
+func (svc *APIKeyService) List(ctx context.Context, userID int64) ([]types.APIKey, error) {
+	ks, err := svc.Repo.List(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return ks, nil
+}
+
+func (svc *APIKeyService) Revoke(ctx context.Context, keyID int64) error {
+	if err := svc.Repo.Revoke(ctx, keyID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return nil
+}
+
+// CreateServiceAccount creates a user for an integration. It has no password and can only
+// authenticate with API keys. Picking its role needs the same permission as assigning one.
+func (svc *APIKeyService) CreateServiceAccount(ctx context.Context, req types.CreateServiceAccountRequest) (types.User, error) {
+	if !serviceAccountName.MatchString(req.Name) {
+		return types.User{}, fmt.Errorf("%w: name may only contain lowercase letters, digits, '-' and '_'", customErrors.InvalidInput)
+	}
+	if err := svc.Policy.Authorize(ctx, auth.PermRolesWrite); err != nil {
+		return types.User{}, err
+	}
+
+	u, err := svc.Users.CreateServiceAccount(ctx, req.Name+serviceAccountDomain, req.Role)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case pgUniqueViolation:
+				return types.User{}, customErrors.AlreadyExists
+			case pgForeignKeyViolation:
+				return types.User{}, fmt.Errorf("%w: unknown role %q", customErrors.InvalidInput, req.Role)
+			}
+		}
+		return types.User{}, fmt.Errorf("failed to create service account: %w", err)
+	}
+	return u, nil
+}
@@ -0,0 +1,155 @@
+package authz
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/repos"
+	repoPermissions "ecom/server/repos/permissions"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// Authorizer is the policy check other services depend on.
+type Authorizer interface {
+	// Authorize returns nil if the caller stored in ctx holds the permission,
+	// customErrors.Unauthorized if there is no caller and customErrors.Forbidden otherwise.
+	// Callers using an API key also need the permission among the key's scopes.
+	// The role checked is the one the user holds now, not the one in their token.
+	Authorize(ctx context.Context, permission string) error
+}
+
+type PolicyService struct {
+	Repo repos.IPermissionRepo
+}
+
+func NewService(repo repos.IPermissionRepo) *PolicyService {
+	return &PolicyService{Repo: repo}
+}
+
+func (svc *PolicyService) Authorize(ctx context.Context, permission string) error {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return customErrors.Unauthorized
+	}
+	if p.APIKeyID != 0 && !slices.Contains(p.Scopes, permission) {
+		return customErrors.Forbidden
+	}
+	allowed, err := svc.Repo.UserHasPermission(ctx, p.UserID, permission)
+	if err != nil {
+		return fmt.Errorf("failed to check permission: %w", err)
+	}
+	if !allowed {
+		return customErrors.Forbidden
+	}
+	return nil
+}
+
+func (svc *PolicyService) ListRoles(ctx context.Context) ([]types.Role, error) {
+	roles, err := svc.Repo.ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (svc *PolicyService) ListPermissions(ctx context.Context) ([]types.Permission, error) {
+	perms, err := svc.Repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	return perms, nil
+}
+
+func (svc *PolicyService) CreateRole(ctx context.Context, req types.CreateRoleRequest) (types.Role, error) {
+	// Check the permissions up front so a typo doesn't leave behind a role without them.
+	if err := svc.checkPermissionsExist(ctx, req.Permissions); err != nil {
+		return types.Role{}, err
+	}
+
+	r, err := svc.Repo.CreateRole(ctx, req.Name, req.Description)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return types.Role{}, customErrors.AlreadyExists
+		}
+		return types.Role{}, fmt.Errorf("failed to create role: %w", err)
+	}
+	if len(req.Permissions) == 0 {
+		return r, nil
+	}
+
+	if err := svc.SetRolePermissions(ctx, r.Name, req.Permissions); err != nil {
+		return types.Role{}, err
+	}
+	r.Permissions = normalizePermissions(req.Permissions)
+	return r, nil
+}
+
+func (svc *PolicyService) SetRolePermissions(ctx context.Context, role string, permissions []string) error {
+	err := svc.Repo.SetRolePermissions(ctx, role, normalizePermissions(permissions))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		if errors.Is(err, repoPermissions.ErrUnknownPermission) {
+			return fmt.Errorf("%w: %w", customErrors.InvalidInput, err)
+		}
+		return fmt.Errorf("failed to set role permissions: %w", err)
+	}
+	return nil
+}
+
+// AssignRole changes the role of a user. Callers can't change their own role,
+// so an admin can't lock themselves out by accident.
+func (svc *PolicyService) AssignRole(ctx context.Context, userID int64, role string) error {
+	if p, ok := auth.PrincipalFromContext(ctx); ok && p.UserID == userID {
+		return fmt.Errorf("%w: can't change your own role", customErrors.Forbidden)
+	}
+
+	err := svc.Repo.AssignUserRole(ctx, userID, role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return fmt.Errorf("%w: unknown role %q", customErrors.InvalidInput, role)
+		}
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+func (svc *PolicyService) checkPermissionsExist(ctx context.Context, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	known, err := svc.ListPermissions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range permissions {
+		if !slices.ContainsFunc(known, func(p types.Permission) bool { return p.Name == name }) {
+			return fmt.Errorf("%w: unknown permission %q", customErrors.InvalidInput, name)
+		}
+	}
+	return nil
+}
+
+// normalizePermissions sorts and removes duplicates.
+func normalizePermissions(permissions []string) []string {
+	perms := slices.Clone(permissions)
+	slices.Sort(perms)
+	return slices.Compact(perms)
+}
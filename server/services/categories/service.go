@@ -0,0 +1,117 @@
+package categories
+
+import (
+	"context"
+	"ecom/server/customErrors"
+	"ecom/server/repos"
+	repoCategories "ecom/server/repos/categories"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+type CategoryService struct {
+	Repo repos.ICategoryRepo
+}
+
+func NewService(repo repos.ICategoryRepo) *CategoryService {
+	return &CategoryService{Repo: repo}
+}
+
+// Tree returns the top level categories by name, each with its subcategories.
+func (svc *CategoryService) Tree(ctx context.Context) ([]types.Category, error) {
+	cs, err := svc.Repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	return buildTree(cs), nil
+}
+
+// buildTree nests a flat list of categories, keeping their order among siblings, and adds the
+// product counts of subcategories to their ancestors.
+func buildTree(cs []types.Category) []types.Category {
+	ids := make(map[int64]bool, len(cs))
+	for _, c := range cs {
+		ids[c.ID] = true
+	}
+	children := make(map[int64][]types.Category)
+	var roots []types.Category
+	for _, c := range cs {
+		if c.ParentID == nil || !ids[*c.ParentID] {
+			roots = append(roots, c)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	var build func(c types.Category) types.Category
+	build = func(c types.Category) types.Category {
+		for _, child := range children[c.ID] {
+			child = build(child)
+			c.ProductCount += child.ProductCount
+			c.Children = append(c.Children, child)
+		}
+		return c
+	}
+	tree := make([]types.Category, 0, len(roots))
+	for _, c := range roots {
+		tree = append(tree, build(c))
+	}
+	return tree
+}
+
+func (svc *CategoryService) Create(ctx context.Context, req types.CategoryRequest) (types.Category, error) {
+	c, err := svc.Repo.Create(ctx, req.Name, req.ParentID)
+	if err != nil {
+		return types.Category{}, mapWriteError(err, "create")
+	}
+	return c, nil
+}
+
+func (svc *CategoryService) Update(ctx context.Context, categoryID int64, req types.CategoryRequest) (types.Category, error) {
+	c, err := svc.Repo.Update(ctx, categoryID, req.Name, req.ParentID)
+	if err != nil {
+		return types.Category{}, mapWriteError(err, "update")
+	}
+	return c, nil
+}
+
+// Delete removes a category and reports how many products were left without one and how many
+// subcategories moved up. With dryRun nothing is deleted.
+func (svc *CategoryService) Delete(ctx context.Context, categoryID int64, dryRun bool) (types.CategoryDeletion, error) {
+	res, err := svc.Repo.Delete(ctx, categoryID, dryRun)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.CategoryDeletion{}, customErrors.NotFound
+		}
+		return types.CategoryDeletion{}, fmt.Errorf("failed to delete category: %w", err)
+	}
+	return res, nil
+}
+
+func mapWriteError(err error, op string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return fmt.Errorf("%w: a category with this name already exists", customErrors.AlreadyExists)
+		case pgForeignKeyViolation:
+			return fmt.Errorf("%w: parent category does not exist", customErrors.InvalidInput)
+		}
+	}
+	if errors.Is(err, repoCategories.ErrCycle) {
+		return fmt.Errorf("%w: a category can't be moved under itself or one of its subcategories", customErrors.InvalidInput)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customErrors.NotFound
+	}
+	return fmt.Errorf("failed to %s category: %w", op, err)
+}
@@ -0,0 +1,40 @@
+package categories
+
+import (
+	"testing"
+
+	"ecom/server/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTree(t *testing.T) {
+	id := func(i int64) *int64 { return &i }
+
+	// Flat, ordered by name as the repo lists them.
+	cs := []types.Category{
+		{ID: 3, Name: "Audio", ParentID: id(1), ProductCount: 1},
+		{ID: 5, Name: "Books", ProductCount: 4},
+		{ID: 1, Name: "Electronics", ProductCount: 2},
+		{ID: 4, Name: "Headphones", ParentID: id(3), ProductCount: 5},
+		{ID: 2, Name: "Laptops", ParentID: id(1), ProductCount: 3},
+		{ID: 6, Name: "Lost", ParentID: id(99)},
+	}
+
+	tree := buildTree(cs)
+
+	require.Len(t, tree, 3)
+	assert.Equal(t, []string{"Books", "Electronics", "Lost"}, []string{tree[0].Name, tree[1].Name, tree[2].Name}, "a category with an unknown parent is shown at the top")
+
+	electronics := tree[1]
+	assert.Equal(t, 11, electronics.ProductCount, "counts include all descendants")
+	require.Len(t, electronics.Children, 2)
+	assert.Equal(t, "Audio", electronics.Children[0].Name)
+	assert.Equal(t, 6, electronics.Children[0].ProductCount)
+	assert.Equal(t, "Headphones", electronics.Children[0].Children[0].Name)
+	assert.Equal(t, "Laptops", electronics.Children[1].Name)
+	assert.Empty(t, tree[0].Children)
+
+	assert.Empty(t, buildTree(nil))
+}
@@ -0,0 +1,111 @@
+package products
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// fewResults is the result count under which a search is retried with corrected spelling.
+	fewResults = 3
+	// maxAlternatives bounds how many synonym variants of a query are searched for.
+	maxAlternatives = 10
+	// maxTermWords is the most words a synonym term is looked up with.
+	maxTermWords = 3
+)
+
+// plainWord matches words that may be spell-checked, unlike search operators such as -excluded or "quoted.
+var plainWord = regexp.MustCompile(`^[\p{L}\p{N}]+$`)
+
+func searchWords(q string) []string {
+	return strings.Fields(strings.ToLower(q))
+}
+
+// phrases returns every run of up to n consecutive words.
+func phrases(words []string, n int) []string {
+	var ps []string
+	for i := range words {
+		for j := i + 1; j <= min(i+n, len(words)); j++ {
+			ps = append(ps, strings.Join(words[i:j], " "))
+		}
+	}
+	return ps
+}
+
+// expandQuery returns the variants of a query with one of its terms replaced by a synonym,
+// for every term found in the groups of synonyms.
+func expandQuery(words []string, groups [][]string) []string {
+	query := strings.Join(words, " ")
+	var alts []string
+	for _, group := range groups {
+		for _, term := range group {
+			tw := strings.Fields(term)
+			for i := 0; i+len(tw) <= len(words); i++ {
+				if !slices.Equal(words[i:i+len(tw)], tw) {
+					continue
+				}
+				for _, syn := range group {
+					if syn == term {
+						continue
+					}
+					alt := strings.Join(slices.Concat(words[:i], []string{syn}, words[i+len(tw):]), " ")
+					if alt != query && !slices.Contains(alts, alt) {
+						alts = append(alts, alt)
+					}
+					if len(alts) == maxAlternatives {
+						return alts
+					}
+				}
+			}
+		}
+	}
+	return alts
+}
+
+// synonyms returns the variants of a query made with the admin-managed synonyms.
+func (svc *ProductService) synonyms(ctx context.Context, q string) ([]string, error) {
+	words := searchWords(q)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	groups, err := svc.Search.FindSynonyms(ctx, phrases(words, maxTermWords))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find synonyms: %w", err)
+	}
+	return expandQuery(words, groups), nil
+}
+
+// correctSpelling returns the query with unknown words replaced by the closest words of the
+// catalog, and whether anything was replaced.
+func (svc *ProductService) correctSpelling(ctx context.Context, q string) (string, bool, error) {
+	words := searchWords(q)
+	var plain []string
+	for _, w := range words {
+		if plainWord.MatchString(w) {
+			plain = append(plain, w)
+		}
+	}
+	if len(plain) == 0 {
+		return q, false, nil
+	}
+
+	suggested, err := svc.Search.SuggestSpellings(ctx, plain)
+	if err != nil {
+		return q, false, fmt.Errorf("failed to suggest spellings: %w", err)
+	}
+	changed := false
+	for i, j := 0, 0; i < len(words) && j < len(suggested); i++ {
+		if !plainWord.MatchString(words[i]) {
+			continue
+		}
+		if words[i] != suggested[j] {
+			words[i] = suggested[j]
+			changed = true
+		}
+		j++
+	}
+	return strings.Join(words, " "), changed, nil
+}
@@ -0,0 +1,189 @@
+package products
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"ecom/server/auth"
+	"ecom/server/repos"
+	repoProducts "ecom/server/repos/products"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhrases(t *testing.T) {
+	assert.Equal(t, []string{"smart", "smart tv", "tv"}, phrases([]string{"smart", "tv"}, 3))
+	assert.Equal(t, []string{"a", "b", "c"}, phrases([]string{"a", "b", "c"}, 1))
+	assert.Empty(t, phrases(nil, 3))
+}
+
+func TestExpandQuery(t *testing.T) {
+	testCases := []struct {
+		name     string
+		words    []string
+		groups   [][]string
+		expected []string
+	}{
+		{
+			name:     "Single word",
+			words:    []string{"cheap", "tv"},
+			groups:   [][]string{{"tv", "television"}},
+			expected: []string{"cheap television"},
+		},
+		{
+			name:     "Several synonyms and words",
+			words:    []string{"tv", "headphones"},
+			groups:   [][]string{{"tv", "television", "telly"}, {"earphones", "headphones"}},
+			expected: []string{"television headphones", "telly headphones", "tv earphones"},
+		},
+		{
+			name:     "Multi-word term",
+			words:    []string{"big", "flat", "screen"},
+			groups:   [][]string{{"tv", "flat screen"}},
+			expected: []string{"big tv"},
+		},
+		{
+			name:   "Term not in the query",
+			words:  []string{"laptop"},
+			groups: [][]string{{"tv", "television"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, expandQuery(tc.words, tc.groups))
+		})
+	}
+
+	t.Run("Bounded", func(t *testing.T) {
+		group := []string{"a"}
+		for _, s := range []string{"b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m"} {
+			group = append(group, s)
+		}
+		assert.Len(t, expandQuery([]string{"a"}, [][]string{group}), maxAlternatives)
+	})
+}
+
+// fakeProductRepo finds the number of products its counts hold for the searched query.
+type fakeProductRepo struct {
+	repos.IProductRepo
+	counts   map[string]int
+	searched []repoProducts.FiltersOptions
+}
+
+func (r *fakeProductRepo) GetAll(ctx context.Context, options repoProducts.GetAllOptions) (repoProducts.GetAllResult, error) {
+	r.searched = append(r.searched, options.Filters)
+	return repoProducts.GetAllResult{TotalCount: r.counts[*options.Filters.SearchString], NextCursor: "9,42"}, nil
+}
+
+type fakeSearchRepo struct {
+	repos.ISearchRepo
+	synonyms    [][]string
+	spellings   map[string]string
+	recorded    []string
+	tokenHashes []string
+}
+
+func (r *fakeSearchRepo) RecordSearch(ctx context.Context, query, correctedQuery string, resultCount int, tokenHash string) error {
+	r.recorded = append(r.recorded, fmt.Sprintf("%s|%s|%d", query, correctedQuery, resultCount))
+	r.tokenHashes = append(r.tokenHashes, tokenHash)
+	return nil
+}
+
+func (r *fakeSearchRepo) FindSynonyms(ctx context.Context, phrases []string) ([][]string, error) {
+	return r.synonyms, nil
+}
+
+func (r *fakeSearchRepo) SuggestSpellings(ctx context.Context, words []string) ([]string, error) {
+	out := make([]string, len(words))
+	for i, w := range words {
+		out[i] = w
+		if s, ok := r.spellings[w]; ok {
+			out[i] = s
+		}
+	}
+	return out, nil
+}
+
+func TestGetAllSearch(t *testing.T) {
+	search := func(svc *ProductService, q string, cursor ...string) repoProducts.GetAllResult {
+		res, err := svc.GetAll(context.Background(), repoProducts.GetAllOptions{
+			Filters:    repoProducts.FiltersOptions{SearchString: &q},
+			Pagination: repoProducts.PaginationOptions{Cursor: cursor},
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Synonyms are searched too", func(t *testing.T) {
+		products := &fakeProductRepo{counts: map[string]int{"TV": 5}}
+		svc := NewService(products, &fakeSearchRepo{synonyms: [][]string{{"tv", "television"}}}, nil)
+
+		res := search(svc, "TV")
+
+		assert.Equal(t, 5, res.TotalCount)
+		assert.Empty(t, res.CorrectedQuery)
+		require.Len(t, products.searched, 1)
+		assert.Equal(t, []string{"television"}, products.searched[0].SearchAlternatives)
+	})
+
+	t.Run("Misspelling is corrected when it finds more", func(t *testing.T) {
+		products := &fakeProductRepo{counts: map[string]int{"hedphones -cheap": 0, "headphones -cheap": 4}}
+		svc := NewService(products, &fakeSearchRepo{spellings: map[string]string{"hedphones": "headphones"}}, nil)
+
+		res := search(svc, "hedphones -cheap")
+
+		assert.Equal(t, 4, res.TotalCount)
+		assert.Equal(t, "headphones -cheap", res.CorrectedQuery)
+	})
+
+	t.Run("Later pages keep the correction", func(t *testing.T) {
+		products := &fakeProductRepo{counts: map[string]int{"lamp": fewResults, "hedphones -cheap": 0, "headphones -cheap": 40}}
+		svc := NewService(products, &fakeSearchRepo{spellings: map[string]string{"hedphones": "headphones"}}, nil)
+		encoded := base64.RawURLEncoding.EncodeToString([]byte("headphones -cheap"))
+
+		assert.Equal(t, "9,42", search(svc, "lamp").NextCursor, "an uncorrected search has a plain cursor")
+		assert.Equal(t, "9,42,"+encoded, search(svc, "hedphones -cheap").NextCursor)
+
+		products.searched = nil
+		res := search(svc, "hedphones -cheap", "9", "42", "headphones -cheap")
+		require.Len(t, products.searched, 1)
+		assert.Equal(t, "headphones -cheap", *products.searched[0].SearchString)
+		assert.Equal(t, "headphones -cheap", res.CorrectedQuery)
+		assert.Equal(t, "9,42,"+encoded, res.NextCursor)
+	})
+
+	t.Run("Correction that doesn't find more is dropped", func(t *testing.T) {
+		products := &fakeProductRepo{counts: map[string]int{"rare": 1, "care": 1}}
+		svc := NewService(products, &fakeSearchRepo{spellings: map[string]string{"rare": "care"}}, nil)
+
+		res := search(svc, "rare")
+
+		assert.Equal(t, 1, res.TotalCount)
+		assert.Empty(t, res.CorrectedQuery)
+	})
+
+	t.Run("Enough results or later pages aren't corrected", func(t *testing.T) {
+		products := &fakeProductRepo{counts: map[string]int{"lamp": fewResults, "lamb": 0}}
+		svc := NewService(products, &fakeSearchRepo{spellings: map[string]string{"lamp": "lamb", "lamb": "lamp"}}, nil)
+
+		assert.Empty(t, search(svc, "lamp").CorrectedQuery)
+		assert.Empty(t, search(svc, "lamb", "1", "2").CorrectedQuery)
+		assert.Len(t, products.searched, 2)
+	})
+
+	t.Run("First pages are recorded once, normalized", func(t *testing.T) {
+		products := &fakeProductRepo{counts: map[string]int{"headphones": 4}}
+		searches := &fakeSearchRepo{spellings: map[string]string{"hedphones": "headphones"}}
+		svc := NewService(products, searches, nil)
+
+		token := search(svc, "  Hedphones ").SearchToken
+		assert.Empty(t, search(svc, "hedphones", "1", "2").SearchToken)
+		assert.Equal(t, []string{"hedphones|headphones|4"}, searches.recorded)
+		assert.Equal(t, []string{auth.HashToken(token)}, searches.tokenHashes, "only the hash of the token is stored")
+		assert.NotEqual(t, token, search(svc, "hedphones").SearchToken, "every search has its own token")
+	})
+}
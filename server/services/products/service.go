@@ -2,22 +2,36 @@ package products
 
 import (
 	"context"
+	"ecom/server/auth"
 	"ecom/server/customErrors"
 	"ecom/server/repos"
 	repoProducts "ecom/server/repos/products"
+	"ecom/server/services/authz"
 	"ecom/server/types"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
 )
 
 type ProductService struct {
-	Repo repos.IProductRepo
+	Repo   repos.IProductRepo
+	Search repos.ISearchRepo // Synonyms and spelling corrections for searches.
+	Policy authz.Authorizer  // Checks that callers may change products.
 }
 
-func NewService(repo repos.IProductRepo) *ProductService {
-	return &ProductService{Repo: repo}
+func NewService(repo repos.IProductRepo, search repos.ISearchRepo, policy authz.Authorizer) *ProductService {
+	return &ProductService{Repo: repo, Search: search, Policy: policy}
 }
 
 func (svc *ProductService) Get(ctx context.Context, productID int64) (types.Product, error) {
@@ -31,11 +45,160 @@ func (svc *ProductService) Get(ctx context.Context, productID int64) (types.Prod
 	return p, nil
 }
 
+// GetAll lists products. A search also finds the synonyms of its terms, and if its first page
+// finds next to nothing, it is retried with corrected spelling; CorrectedQuery tells if that was used,
+// and NextCursor carries it so that the later pages search the same.
+// The first page of a search is logged for the search analytics.
 func (svc *ProductService) GetAll(ctx context.Context, options repoProducts.GetAllOptions) (repoProducts.GetAllResult, error) {
+	res, err := svc.find(ctx, options)
+	if err != nil {
+		return res, err
+	}
+	if res.CorrectedQuery != "" && res.NextCursor != "" {
+		res.NextCursor += "," + base64.RawURLEncoding.EncodeToString([]byte(res.CorrectedQuery))
+	}
+	if options.Filters.SearchString == nil || len(options.Pagination.Cursor) > 0 {
+		return res, nil
+	}
+
+	// A search that can't be logged still has its results.
+	query := strings.Join(strings.Fields(strings.ToLower(*options.Filters.SearchString)), " ")
+	token, hash, err := auth.NewOpaqueToken()
+	if err == nil {
+		err = svc.Search.RecordSearch(ctx, query, res.CorrectedQuery, res.TotalCount, hash)
+	}
+	if err != nil {
+		log.Printf("failed to record search %q: %v", query, err)
+		return res, nil
+	}
+	res.SearchToken = token
+	return res, nil
+}
+
+func (svc *ProductService) find(ctx context.Context, options repoProducts.GetAllOptions) (repoProducts.GetAllResult, error) {
+	if options.Filters.SearchString == nil {
+		return svc.getAll(ctx, options)
+	}
+
+	// Later pages of a corrected search go on with the correction of the first one.
+	if cursor := options.Pagination.Cursor; len(cursor) == 3 {
+		options.Pagination.Cursor = cursor[:2]
+		res, err := svc.search(ctx, options, cursor[2])
+		if err != nil {
+			return repoProducts.GetAllResult{}, err
+		}
+		res.CorrectedQuery = cursor[2]
+		return res, nil
+	}
+
+	q := *options.Filters.SearchString
+	res, err := svc.search(ctx, options, q)
+	if err != nil || res.TotalCount >= fewResults || len(options.Pagination.Cursor) > 0 {
+		return res, err
+	}
+
+	corrected, changed, err := svc.correctSpelling(ctx, q)
+	if err != nil || !changed {
+		return res, err
+	}
+	correctedRes, err := svc.search(ctx, options, corrected)
+	if err != nil {
+		return repoProducts.GetAllResult{}, err
+	}
+	if correctedRes.TotalCount <= res.TotalCount {
+		return res, nil
+	}
+	correctedRes.CorrectedQuery = corrected
+	return correctedRes, nil
+}
+
+func (svc *ProductService) search(ctx context.Context, options repoProducts.GetAllOptions, q string) (repoProducts.GetAllResult, error) {
+	alts, err := svc.synonyms(ctx, q)
+	if err != nil {
+		return repoProducts.GetAllResult{}, err
+	}
+	options.Filters.SearchString = &q
+	options.Filters.SearchAlternatives = alts
+	return svc.getAll(ctx, options)
+}
+
+func (svc *ProductService) getAll(ctx context.Context, options repoProducts.GetAllOptions) (repoProducts.GetAllResult, error) {
 	res, err := svc.Repo.GetAll(ctx, options)
 	if err != nil {
-		// The service layer can add more context or logic here.
 		return repoProducts.GetAllResult{}, fmt.Errorf("failed to get all products: %w", err)
 	}
 	return res, nil
 }
+
+// Suggest returns up to limit products, and fewer categories, whose names match the typed text.
+func (svc *ProductService) Suggest(ctx context.Context, req types.SuggestRequest) (types.Suggestions, error) {
+	limit := 8
+	if req.Limit > 0 {
+		limit = req.Limit
+	}
+	res, err := svc.Repo.Suggest(ctx, strings.TrimSpace(req.Query), limit)
+	if err != nil {
+		return types.Suggestions{}, fmt.Errorf("failed to get suggestions: %w", err)
+	}
+	return res, nil
+}
+
+// Create adds a product and returns it as shown to customers.
+func (svc *ProductService) Create(ctx context.Context, req types.ProductRequest) (types.Product, error) {
+	if err := svc.Policy.Authorize(ctx, auth.PermProductsWrite); err != nil {
+		return types.Product{}, err
+	}
+	id, err := svc.Repo.Create(ctx, req)
+	if err != nil {
+		return types.Product{}, mapWriteError(err, "create")
+	}
+	return svc.Get(ctx, id)
+}
+
+// Update replaces a product and returns it as shown to customers.
+func (svc *ProductService) Update(ctx context.Context, productID int64, req types.ProductRequest) (types.Product, error) {
+	if err := svc.Policy.Authorize(ctx, auth.PermProductsWrite); err != nil {
+		return types.Product{}, err
+	}
+	if err := svc.Repo.Update(ctx, productID, req); err != nil {
+		return types.Product{}, mapWriteError(err, "update")
+	}
+	return svc.Get(ctx, productID)
+}
+
+// Delete removes a product. Products that were ordered are kept for the order history.
+func (svc *ProductService) Delete(ctx context.Context, productID int64) error {
+	if err := svc.Policy.Authorize(ctx, auth.PermProductsWrite); err != nil {
+		return err
+	}
+	if err := svc.Repo.Delete(ctx, productID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return fmt.Errorf("%w: the product was ordered and can't be deleted", customErrors.Conflict)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+	return nil
+}
+
+// mapWriteError maps the constraint violations of a product insert or update to errors for the client.
+func mapWriteError(err error, op string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return fmt.Errorf("%w: a product with this name already exists", customErrors.AlreadyExists)
+		case pgForeignKeyViolation:
+			return fmt.Errorf("%w: category does not exist", customErrors.InvalidInput)
+		case pgCheckViolation:
+			return fmt.Errorf("%w: a value is out of the allowed range", customErrors.InvalidInput)
+		}
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return customErrors.NotFound
+	}
+	return fmt.Errorf("failed to %s product: %w", op, err)
+}
@@ -0,0 +1,42 @@
+package products
+
+import (
+	"context"
+	"ecom/server/customErrors"
+	"ecom/server/types"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePolicy grants the permissions it holds.
+type fakePolicy struct {
+	granted []string
+}
+
+func (p *fakePolicy) Authorize(ctx context.Context, permission string) error {
+	for _, g := range p.granted {
+		if g == permission {
+			return nil
+		}
+	}
+	return customErrors.Forbidden
+}
+
+func TestWritesNeedPermission(t *testing.T) {
+	svc := NewService(nil, nil, &fakePolicy{granted: []string{"orders:read"}})
+	ctx := context.Background()
+
+	_, err := svc.Create(ctx, types.ProductRequest{Name: "Lamp"})
+	assert.ErrorIs(t, err, customErrors.Forbidden)
+	_, err = svc.Update(ctx, 1, types.ProductRequest{Name: "Lamp"})
+	assert.ErrorIs(t, err, customErrors.Forbidden)
+	assert.ErrorIs(t, svc.Delete(ctx, 1), customErrors.Forbidden)
+}
+
+func TestMapWriteError(t *testing.T) {
+	assert.ErrorIs(t, mapWriteError(&pgconn.PgError{Code: pgCheckViolation}, "create"), customErrors.InvalidInput, "e.g. a negative price")
+	assert.ErrorIs(t, mapWriteError(&pgconn.PgError{Code: pgUniqueViolation}, "create"), customErrors.AlreadyExists)
+	assert.ErrorIs(t, mapWriteError(&pgconn.PgError{Code: pgForeignKeyViolation}, "update"), customErrors.InvalidInput)
+}
@@ -0,0 +1,53 @@
+package search
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const pgForeignKeyViolation = "23503"
+
+// clickWindow is how long after a search the clicks on its results are counted.
+const clickWindow = time.Hour
+
+// RecordClick notes that a product was opened from the results of a recent logged search.
+func (svc *SearchService) RecordClick(ctx context.Context, productID int64, req types.SearchClickRequest) error {
+	err := svc.Repo.RecordClick(ctx, auth.HashToken(req.SearchToken), productID, time.Now().Add(-clickWindow))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: unknown or expired search", customErrors.InvalidInput)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return fmt.Errorf("%w: unknown product", customErrors.InvalidInput)
+		}
+		return fmt.Errorf("failed to record click: %w", err)
+	}
+	return nil
+}
+
+// Analytics reports on the searches of the last days, 30 by default, with up to limit queries
+// per report, 20 by default.
+func (svc *SearchService) Analytics(ctx context.Context, req types.SearchAnalyticsRequest) (types.SearchAnalytics, error) {
+	days, limit := 30, 20
+	if req.Days > 0 {
+		days = req.Days
+	}
+	if req.Limit > 0 {
+		limit = req.Limit
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	res, err := svc.Repo.SearchAnalytics(ctx, since, limit)
+	if err != nil {
+		return types.SearchAnalytics{}, fmt.Errorf("failed to get search analytics: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"context"
+	"ecom/server/customErrors"
+	"ecom/server/repos"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SearchService manages how product searches are interpreted.
+type SearchService struct {
+	Repo repos.ISearchRepo
+}
+
+func NewService(repo repos.ISearchRepo) *SearchService {
+	return &SearchService{Repo: repo}
+}
+
+// normalizeTerms lowercases the terms and collapses their whitespace, as searches are matched
+// against them that way, and drops duplicates.
+func normalizeTerms(terms []string) ([]string, error) {
+	var norm []string
+	for _, t := range terms {
+		t = strings.Join(strings.Fields(strings.ToLower(t)), " ")
+		if t != "" && !slices.Contains(norm, t) {
+			norm = append(norm, t)
+		}
+	}
+	if len(norm) < 2 {
+		return nil, fmt.Errorf("%w: a synonym group needs at least two different terms", customErrors.InvalidInput)
+	}
+	return norm, nil
+}
+
+func (svc *SearchService) ListSynonyms(ctx context.Context) ([]types.SynonymGroup, error) {
+	gs, err := svc.Repo.ListSynonyms(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list synonyms: %w", err)
+	}
+	return gs, nil
+}
+
+func (svc *SearchService) CreateSynonyms(ctx context.Context, req types.SynonymGroupRequest) (types.SynonymGroup, error) {
+	terms, err := normalizeTerms(req.Terms)
+	if err != nil {
+		return types.SynonymGroup{}, err
+	}
+	g, err := svc.Repo.CreateSynonyms(ctx, terms)
+	if err != nil {
+		return types.SynonymGroup{}, fmt.Errorf("failed to create synonyms: %w", err)
+	}
+	return g, nil
+}
+
+func (svc *SearchService) UpdateSynonyms(ctx context.Context, groupID int64, req types.SynonymGroupRequest) (types.SynonymGroup, error) {
+	terms, err := normalizeTerms(req.Terms)
+	if err != nil {
+		return types.SynonymGroup{}, err
+	}
+	g, err := svc.Repo.UpdateSynonyms(ctx, groupID, terms)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.SynonymGroup{}, customErrors.NotFound
+		}
+		return types.SynonymGroup{}, fmt.Errorf("failed to update synonyms: %w", err)
+	}
+	return g, nil
+}
+
+func (svc *SearchService) DeleteSynonyms(ctx context.Context, groupID int64) error {
+	if err := svc.Repo.DeleteSynonyms(ctx, groupID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		return fmt.Errorf("failed to delete synonyms: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package users
+
+import (
+	"context"
+	"ecom/server/customErrors"
+	repoUsers "ecom/server/repos/users"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxAccountFailures = 5              // Failures of one account before it gets locked.
+	maxIPFailures      = 20             // Failures from one IP, across accounts, before it gets throttled.
+	lockoutBase        = time.Minute    // First lock; it doubles with every further failure.
+	lockoutMax         = time.Hour      // Upper bound of a lock.
+	failureWindow      = 24 * time.Hour // Counters start over after this long without failures.
+)
+
+// lockoutDuration returns how long to lock after failures, given the failures allowed.
+func lockoutDuration(failures, allowed int) time.Duration {
+	if failures < allowed {
+		return 0
+	}
+	extra := failures - allowed
+	if extra >= 16 { // Avoid overflowing the shift, the cap applies long before.
+		return lockoutMax
+	}
+	return min(lockoutBase<<extra, lockoutMax)
+}
+
+// checkLoginLocks fails if the account or the client IP is currently locked.
+func (svc *UserService) checkLoginLocks(ctx context.Context, email, ip string) error {
+	until, err := svc.Repo.GetLockedUntil(ctx, repoUsers.AttemptScopeAccount, email)
+	if err != nil {
+		return fmt.Errorf("failed to check account lock: %w", err)
+	}
+	if wait := time.Until(until); wait > 0 {
+		return &customErrors.RetryAfterError{Err: customErrors.Locked, RetryAfter: wait}
+	}
+
+	until, err = svc.Repo.GetLockedUntil(ctx, repoUsers.AttemptScopeIP, ip)
+	if err != nil {
+		return fmt.Errorf("failed to check ip lock: %w", err)
+	}
+	if wait := time.Until(until); wait > 0 {
+		return &customErrors.RetryAfterError{Err: customErrors.TooManyRequests, RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login against the account and the IP, locking them
+// once over their limit. It returns the error to report to the client.
+func (svc *UserService) recordLoginFailure(ctx context.Context, email, ip string) error {
+	lockErr := error(customErrors.InvalidCredentials)
+
+	scopes := []struct {
+		scope, key string
+		allowed    int
+		err        error
+	}{
+		{repoUsers.AttemptScopeIP, ip, maxIPFailures, customErrors.TooManyRequests},
+		{repoUsers.AttemptScopeAccount, email, maxAccountFailures, customErrors.Locked},
+	}
+	for _, s := range scopes {
+		failures, err := svc.Repo.RecordLoginFailure(ctx, s.scope, s.key, failureWindow)
+		if err != nil {
+			return fmt.Errorf("failed to record login failure: %w", err)
+		}
+		if d := lockoutDuration(failures, s.allowed); d > 0 {
+			if err := svc.Repo.LockLogin(ctx, s.scope, s.key, time.Now().Add(d)); err != nil {
+				return fmt.Errorf("failed to lock login: %w", err)
+			}
+			lockErr = &customErrors.RetryAfterError{Err: s.err, RetryAfter: d}
+		}
+	}
+	return lockErr
+}
+
+// UnlockAccount lifts the lock and clears the failed login counter of a user's account.
+func (svc *UserService) UnlockAccount(ctx context.Context, userID int64) error {
+	u, err := svc.Repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if err := svc.Repo.ClearLoginFailures(ctx, repoUsers.AttemptScopeAccount, strings.ToLower(u.Email)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package users
+
+import (
+	"context"
+	"ecom/server/customErrors"
+	"ecom/server/repos"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	testCases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 1, expected: 0},
+		{failures: 4, expected: 0},
+		{failures: 5, expected: time.Minute},
+		{failures: 6, expected: 2 * time.Minute},
+		{failures: 8, expected: 8 * time.Minute},
+		{failures: 11, expected: lockoutMax}, // 64 minutes, capped
+		{failures: 500, expected: lockoutMax},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, lockoutDuration(tc.failures, 5), "failures: %d", tc.failures)
+	}
+}
+
+// attemptRepo keeps the login_attempts counters in memory. No email belongs to an account.
+type attemptRepo struct {
+	repos.IUserRepo
+	counts map[string]int
+	locked map[string]time.Time
+}
+
+func (r *attemptRepo) GetLockedUntil(_ context.Context, scope, key string) (time.Time, error) {
+	return r.locked[scope+"|"+key], nil
+}
+
+func (r *attemptRepo) RecordLoginFailure(_ context.Context, scope, key string, _ time.Duration) (int, error) {
+	r.counts[scope+"|"+key]++
+	return r.counts[scope+"|"+key], nil
+}
+
+func (r *attemptRepo) LockLogin(_ context.Context, scope, key string, until time.Time) error {
+	r.locked[scope+"|"+key] = until
+	return nil
+}
+
+func (r *attemptRepo) GetByEmail(context.Context, string) (types.User, error) {
+	return types.User{}, pgx.ErrNoRows
+}
+
+func TestSendMagicLinkThrottle(t *testing.T) {
+	newService := func() *UserService {
+		return &UserService{Repo: &attemptRepo{counts: map[string]int{}, locked: map[string]time.Time{}}}
+	}
+	ctx := context.Background()
+	home := Client{IP: "10.0.0.1"}
+
+	t.Run("Per address", func(t *testing.T) {
+		svc := newService()
+		for i := 0; i < maxMagicLinksPerEmail; i++ {
+			require.NoError(t, svc.SendMagicLink(ctx, "ann@example.com", home))
+		}
+
+		err := svc.SendMagicLink(ctx, " Ann@example.com", Client{IP: "10.0.0.2"})
+		assert.ErrorIs(t, err, customErrors.TooManyRequests, "the address is throttled from any IP")
+		var retry *customErrors.RetryAfterError
+		require.True(t, errors.As(err, &retry))
+		assert.Positive(t, retry.RetryAfter)
+
+		assert.NoError(t, svc.SendMagicLink(ctx, "bob@example.com", home), "other addresses aren't")
+	})
+
+	t.Run("Per IP", func(t *testing.T) {
+		svc := newService()
+		for i := 0; i < maxMagicLinksPerIP; i++ {
+			require.NoError(t, svc.SendMagicLink(ctx, fmt.Sprintf("user%d@example.com", i), home))
+		}
+
+		err := svc.SendMagicLink(ctx, "new@example.com", home)
+		assert.ErrorIs(t, err, customErrors.TooManyRequests)
+		assert.NoError(t, svc.SendMagicLink(ctx, "new@example.com", Client{IP: "10.0.0.2"}))
+	})
+}
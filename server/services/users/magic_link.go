@@ -0,0 +1,114 @@
+package users
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	repoUsers "ecom/server/repos/users"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	magicLinkTTL          = 15 * time.Minute
+	maxMagicLinksPerEmail = 3         // Links to one address before further requests are throttled.
+	maxMagicLinksPerIP    = 10        // Links requested from one IP, across addresses.
+	magicLinkWindow       = time.Hour // Counters start over after this long without requests.
+)
+
+// SendMagicLink mails a one-time login link if the email belongs to an account.
+// Like ForgotPassword, it reports success either way. Requests are throttled per address
+// and per client IP like failed logins, so the endpoint can't be used to flood a mailbox.
+func (svc *UserService) SendMagicLink(ctx context.Context, email string, client Client) error {
+	email = strings.TrimSpace(email)
+	if err := svc.throttleMagicLinks(ctx, strings.ToLower(email), client.IP); err != nil {
+		return err
+	}
+
+	u, err := svc.Repo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, err := svc.issueUserToken(ctx, u.ID, repoUsers.TokenMagicLink, "", magicLinkTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Open this link to log in:\n\n%s\n\n"+
+		"The link works once and expires in %s. If you didn't ask for it, you can ignore this email.\n",
+		svc.link("/magic-login", token), magicLinkTTL)
+	if err := svc.sendMail(ctx, u.Email, "Your login link", body); err != nil {
+		log.Printf("magic link for user %d: %v", u.ID, err)
+	}
+	return nil
+}
+
+// throttleMagicLinks fails with customErrors.TooManyRequests while the address or the IP is
+// throttled, and otherwise counts the request, throttling them once over their limit.
+func (svc *UserService) throttleMagicLinks(ctx context.Context, email, ip string) error {
+	scopes := []struct {
+		scope, key string
+		allowed    int
+	}{
+		{repoUsers.AttemptScopeMagicLinkEmail, email, maxMagicLinksPerEmail},
+		{repoUsers.AttemptScopeMagicLinkIP, ip, maxMagicLinksPerIP},
+	}
+	for _, s := range scopes {
+		until, err := svc.Repo.GetLockedUntil(ctx, s.scope, s.key)
+		if err != nil {
+			return fmt.Errorf("failed to check magic link throttle: %w", err)
+		}
+		if wait := time.Until(until); wait > 0 {
+			return &customErrors.RetryAfterError{Err: customErrors.TooManyRequests, RetryAfter: wait}
+		}
+	}
+	for _, s := range scopes {
+		requests, err := svc.Repo.RecordLoginFailure(ctx, s.scope, s.key, magicLinkWindow)
+		if err != nil {
+			return fmt.Errorf("failed to count magic link request: %w", err)
+		}
+		if d := lockoutDuration(requests, s.allowed); d > 0 {
+			if err := svc.Repo.LockLogin(ctx, s.scope, s.key, time.Now().Add(d)); err != nil {
+				return fmt.Errorf("failed to throttle magic links: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// LoginMagicLink logs in the user a magic link was issued to. Users with two-factor
+// authentication get an MFA challenge, as with a password login.
+func (svc *UserService) LoginMagicLink(ctx context.Context, token string, client Client) (AuthResult, error) {
+	userID, _, err := svc.Repo.ConsumeUserToken(ctx, repoUsers.TokenMagicLink, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AuthResult{}, fmt.Errorf("%w: invalid or expired login link", customErrors.Unauthorized)
+		}
+		return AuthResult{}, fmt.Errorf("failed to consume magic link token: %w", err)
+	}
+	u, err := svc.Repo.Get(ctx, userID)
+	if err != nil {
+		return AuthResult{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Receiving the link proves ownership of the address.
+	if !u.EmailVerified {
+		if err := svc.Repo.MarkEmailVerified(ctx, u.ID); err != nil {
+			return AuthResult{}, fmt.Errorf("failed to mark email verified: %w", err)
+		}
+		u.EmailVerified = true
+	}
+
+	if u.TOTPEnabled {
+		return svc.mfaChallenge(u)
+	}
+	return svc.startSession(ctx, u, false, client)
+}
@@ -0,0 +1,156 @@
+package users
+
+import (
+	"context"
+	"crypto/subtle"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/oidc"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// OIDCStateTTL bounds the time a user may spend at the provider.
+const OIDCStateTTL = 10 * time.Minute
+
+// maxEmailLen is the size of the users.email column.
+const maxEmailLen = 60
+
+// OIDCProvider is the part of an oidc.Client the login flow uses.
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (oidc.Claims, error)
+}
+
+func (svc *UserService) oidcProvider(name string) (OIDCProvider, error) {
+	p, ok := svc.OIDC[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown login provider %q", customErrors.NotFound, name)
+	}
+	return p, nil
+}
+
+// StartOIDC begins a login with a provider. It returns the URL to send the browser to and
+// the state, which the browser must keep and present again to FinishOIDC.
+func (svc *UserService) StartOIDC(ctx context.Context, provider string) (url, state string, err error) {
+	p, err := svc.oidcProvider(provider)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, stateHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := auth.NewID()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+	if err := svc.Repo.CreateOIDCState(ctx, stateHash, provider, nonce, verifier, time.Now().Add(OIDCStateTTL)); err != nil {
+		return "", "", fmt.Errorf("failed to store oidc state: %w", err)
+	}
+
+	url, err = p.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build %s authorization url: %w", provider, err)
+	}
+	return url, state, nil
+}
+
+// FinishOIDC completes a login when the provider redirects back. The provider account is
+// matched to a user by its link, else by email, else a new user is created. Linking by
+// email requires the provider to vouch for the address, or anyone could register an
+// account at some provider with a victim's email and take over their account.
+// browserState is the state the browser kept from StartOIDC. It must match the returned state,
+// or an attacker could have a victim finish the attacker's login and be logged into their account.
+func (svc *UserService) FinishOIDC(ctx context.Context, provider, state, browserState, code string, client Client) (AuthResult, error) {
+	p, err := svc.oidcProvider(provider)
+	if err != nil {
+		return AuthResult{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return AuthResult{}, fmt.Errorf("%w: login was not started from this browser", customErrors.Unauthorized)
+	}
+
+	nonce, verifier, err := svc.Repo.ConsumeOIDCState(ctx, auth.HashToken(state), provider)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AuthResult{}, fmt.Errorf("%w: invalid or expired login attempt", customErrors.Unauthorized)
+		}
+		return AuthResult{}, fmt.Errorf("failed to consume oidc state: %w", err)
+	}
+	claims, err := p.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		log.Printf("%s login failed: %v", provider, err)
+		return AuthResult{}, fmt.Errorf("%w: login with %s failed", customErrors.Unauthorized, provider)
+	}
+
+	u, err := svc.userForIdentity(ctx, provider, claims)
+	if err != nil {
+		return AuthResult{}, err
+	}
+	if u.TOTPEnabled {
+		return svc.mfaChallenge(u)
+	}
+	return svc.startSession(ctx, u, false, client)
+}
+
+func (svc *UserService) userForIdentity(ctx context.Context, provider string, claims oidc.Claims) (types.User, error) {
+	u, err := svc.Repo.GetByIdentity(ctx, provider, claims.Subject)
+	if err == nil {
+		return u, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return types.User{}, fmt.Errorf("failed to get user by identity: %w", err)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" {
+		return types.User{}, fmt.Errorf("%w: %s didn't share an email address", customErrors.InvalidInput, provider)
+	}
+	if len(email) > maxEmailLen {
+		return types.User{}, fmt.Errorf("%w: email address is too long", customErrors.InvalidInput)
+	}
+
+	u, err = svc.Repo.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !claims.EmailVerified {
+			return types.User{}, fmt.Errorf("%w: an account with this email already exists, log in with your password", customErrors.AlreadyExists)
+		}
+		if err := svc.Repo.LinkIdentity(ctx, u.ID, provider, claims.Subject, email); err != nil {
+			return types.User{}, fmt.Errorf("failed to link identity: %w", err)
+		}
+		if !u.EmailVerified {
+			if err := svc.Repo.MarkEmailVerified(ctx, u.ID); err != nil {
+				return types.User{}, fmt.Errorf("failed to mark email verified: %w", err)
+			}
+			u.EmailVerified = true
+		}
+		return u, nil
+	case errors.Is(err, pgx.ErrNoRows):
+		u, err := svc.Repo.CreateWithIdentity(ctx, email, claims.Name, claims.EmailVerified, provider, claims.Subject)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+				// Lost a race against a concurrent signup or login.
+				return types.User{}, customErrors.AlreadyExists
+			}
+			return types.User{}, fmt.Errorf("failed to create user: %w", err)
+		}
+		return u, nil
+	default:
+		return types.User{}, fmt.Errorf("failed to get user: %w", err)
+	}
+}
@@ -0,0 +1,183 @@
+package users
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/oidc"
+	"ecom/server/oidc/oidctest"
+	"ecom/server/repos"
+	repoUsers "ecom/server/repos/users"
+	"ecom/server/types"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oidcRepo keeps what the OIDC login touches in memory. Other methods are left to the
+// embedded nil interface and panic if called.
+type oidcRepo struct {
+	repos.IUserRepo
+	states     map[string][2]string // state hash -> nonce, verifier
+	users      []types.User
+	identities map[string]int64 // provider/subject -> user id
+}
+
+func newOIDCRepo() *oidcRepo {
+	return &oidcRepo{states: map[string][2]string{}, identities: map[string]int64{}}
+}
+
+func (r *oidcRepo) CreateOIDCState(_ context.Context, stateHash, _, nonce, verifier string, _ time.Time) error {
+	r.states[stateHash] = [2]string{nonce, verifier}
+	return nil
+}
+
+func (r *oidcRepo) ConsumeOIDCState(_ context.Context, stateHash, _ string) (string, string, error) {
+	s, ok := r.states[stateHash]
+	if !ok {
+		return "", "", pgx.ErrNoRows
+	}
+	delete(r.states, stateHash)
+	return s[0], s[1], nil
+}
+
+func (r *oidcRepo) GetByIdentity(_ context.Context, provider, subject string) (types.User, error) {
+	if id, ok := r.identities[provider+"/"+subject]; ok {
+		return r.users[id-1], nil
+	}
+	return types.User{}, pgx.ErrNoRows
+}
+
+func (r *oidcRepo) GetByEmail(_ context.Context, email string) (types.User, error) {
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return types.User{}, pgx.ErrNoRows
+}
+
+func (r *oidcRepo) LinkIdentity(_ context.Context, userID int64, provider, subject, _ string) error {
+	r.identities[provider+"/"+subject] = userID
+	return nil
+}
+
+func (r *oidcRepo) CreateWithIdentity(_ context.Context, email, name string, verified bool, provider, subject string) (types.User, error) {
+	u := types.User{ID: int64(len(r.users) + 1), Email: email, Name: name, EmailVerified: verified, Role: "user"}
+	r.users = append(r.users, u)
+	r.identities[provider+"/"+subject] = u.ID
+	return u, nil
+}
+
+func (r *oidcRepo) MarkEmailVerified(_ context.Context, userID int64) error {
+	r.users[userID-1].EmailVerified = true
+	return nil
+}
+
+func (r *oidcRepo) CreateRefreshToken(_ context.Context, t repoUsers.RefreshToken, _ string) (repoUsers.RefreshToken, error) {
+	t.ID = 1
+	return t, nil
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := oidctest.NewServer("client", "secret", oidctest.User{})
+	defer idp.Close()
+
+	repo := newOIDCRepo()
+	provider := oidc.NewClient(oidc.Config{
+		Issuer:       idp.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://api.test/v1/auth/oidc/test/callback",
+	}, idp.Client())
+	svc := NewService(repo, auth.NewJWTManager("secret", time.Minute), nil, nil,
+		map[string]OIDCProvider{"test": provider}, Config{RefreshTTL: time.Hour})
+	ctx := context.Background()
+
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	// login runs the flow for the provider's current user and returns the callback parameters.
+	login := func(t *testing.T) (state, code string) {
+		t.Helper()
+		authURL, _, err := svc.StartOIDC(ctx, "test")
+		require.NoError(t, err)
+		res, err := browser.Get(authURL)
+		require.NoError(t, err)
+		res.Body.Close()
+		loc, err := url.Parse(res.Header.Get("Location"))
+		require.NoError(t, err)
+		return loc.Query().Get("state"), loc.Query().Get("code")
+	}
+
+	t.Run("New user", func(t *testing.T) {
+		idp.SetUser(oidctest.User{Subject: "1", Email: "new@example.com", EmailVerified: true, Name: "New"})
+		state, code := login(t)
+		res, err := svc.FinishOIDC(ctx, "test", state, state, code, Client{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, res.AccessToken)
+		assert.Equal(t, "new@example.com", res.User.Email)
+		assert.True(t, res.User.EmailVerified)
+
+		_, err = svc.FinishOIDC(ctx, "test", state, state, code, Client{})
+		assert.ErrorIs(t, err, customErrors.Unauthorized, "a state can only be used once")
+	})
+
+	t.Run("Returning user", func(t *testing.T) {
+		state, code := login(t)
+		res, err := svc.FinishOIDC(ctx, "test", state, state, code, Client{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), res.User.ID)
+		assert.Len(t, repo.users, 1)
+	})
+
+	t.Run("Links an existing account by verified email", func(t *testing.T) {
+		repo.users = append(repo.users, types.User{ID: 2, Email: "jane@example.com", Role: "user"})
+		idp.SetUser(oidctest.User{Subject: "2", Email: "jane@example.com", EmailVerified: true})
+		state, code := login(t)
+		res, err := svc.FinishOIDC(ctx, "test", state, state, code, Client{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), res.User.ID)
+		assert.Equal(t, int64(2), repo.identities["test/2"])
+	})
+
+	t.Run("Won't link an unverified email", func(t *testing.T) {
+		repo.users = append(repo.users, types.User{ID: 3, Email: "bob@example.com", Role: "user"})
+		idp.SetUser(oidctest.User{Subject: "3", Email: "bob@example.com", EmailVerified: false})
+		state, code := login(t)
+		_, err := svc.FinishOIDC(ctx, "test", state, state, code, Client{})
+		assert.ErrorIs(t, err, customErrors.AlreadyExists)
+		assert.NotContains(t, repo.identities, "test/3")
+	})
+
+	t.Run("Emails are stored lowercased", func(t *testing.T) {
+		idp.SetUser(oidctest.User{Subject: "4", Email: " Mixed@Example.COM", EmailVerified: true})
+		state, code := login(t)
+		res, err := svc.FinishOIDC(ctx, "test", state, state, code, Client{})
+		require.NoError(t, err)
+		assert.Equal(t, "mixed@example.com", res.User.Email)
+	})
+
+	t.Run("Callback from another browser", func(t *testing.T) {
+		idp.SetUser(oidctest.User{Subject: "1", Email: "new@example.com", EmailVerified: true})
+		state, code := login(t)
+		_, err := svc.FinishOIDC(ctx, "test", state, "", code, Client{})
+		assert.ErrorIs(t, err, customErrors.Unauthorized, "a victim opening the attacker's callback has no state cookie")
+		_, err = svc.FinishOIDC(ctx, "test", state, "other", code, Client{})
+		assert.ErrorIs(t, err, customErrors.Unauthorized)
+
+		res, err := svc.FinishOIDC(ctx, "test", state, state, code, Client{})
+		require.NoError(t, err, "the browser that started the login can still finish it")
+		assert.Equal(t, int64(1), res.User.ID)
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		_, _, err := svc.StartOIDC(ctx, "other")
+		assert.ErrorIs(t, err, customErrors.NotFound)
+	})
+}
@@ -0,0 +1,81 @@
+package users
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	repoUsers "ecom/server/repos/users"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const accountDeletionTTL = time.Hour
+
+// ExportMe returns everything stored about the caller.
+func (svc *UserService) ExportMe(ctx context.Context) (types.AccountExport, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return types.AccountExport{}, err
+	}
+	ex, err := svc.Repo.Export(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.AccountExport{}, customErrors.NotFound
+		}
+		return types.AccountExport{}, fmt.Errorf("failed to export user data: %w", err)
+	}
+	if ex.Sessions, err = svc.Repo.ListSessions(ctx, userID); err != nil {
+		return types.AccountExport{}, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	ex.ExportedAt = time.Now()
+	return ex, nil
+}
+
+// RequestAccountDeletion mails the caller a link confirming they want their account deleted,
+// so a stolen access token alone can't delete it.
+func (svc *UserService) RequestAccountDeletion(ctx context.Context) error {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+	u, err := svc.Repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if u.DeletedAt != nil {
+		return customErrors.NotFound
+	}
+
+	token, err := svc.issueUserToken(ctx, u.ID, repoUsers.TokenAccountDeletion, "", accountDeletionTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("We received a request to delete your account. Open this link to confirm:\n\n%s\n\n"+
+		"Your profile, addresses, wishlist and cart will be deleted and your orders kept without your personal details. "+
+		"This can't be undone. The link expires in %s; if you didn't ask for it, you can ignore this email.\n",
+		svc.link("/confirm-account-deletion", token), accountDeletionTTL)
+	return svc.sendMail(ctx, u.Email, "Confirm the deletion of your account", body)
+}
+
+// ConfirmAccountDeletion anonymizes the account the token was issued for, ending all its sessions.
+// It fails with Conflict while an order of the user is still in progress, leaving the token usable
+// for once the order is closed.
+func (svc *UserService) ConfirmAccountDeletion(ctx context.Context, token string) error {
+	if err := svc.Repo.Anonymize(ctx, auth.HashToken(token)); err != nil {
+		if errors.Is(err, repoUsers.ErrOpenOrders) {
+			return fmt.Errorf("%w: orders in progress must be delivered or cancelled first", customErrors.Conflict)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: invalid or expired token", customErrors.InvalidInput)
+		}
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package users
+
+import (
+	"context"
+	"ecom/server/customErrors"
+	"ecom/server/repos"
+	repoUsers "ecom/server/repos/users"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type anonymizeRepo struct {
+	repos.IUserRepo
+	err error
+}
+
+func (r *anonymizeRepo) Anonymize(context.Context, string) error {
+	return r.err
+}
+
+func TestConfirmAccountDeletion(t *testing.T) {
+	testCases := []struct {
+		name     string
+		repoErr  error
+		expected error
+	}{
+		{name: "Deleted", repoErr: nil, expected: nil},
+		{name: "Orders in progress", repoErr: repoUsers.ErrOpenOrders, expected: customErrors.Conflict},
+		{name: "Invalid token", repoErr: pgx.ErrNoRows, expected: customErrors.InvalidInput},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &UserService{Repo: &anonymizeRepo{err: tc.repoErr}}
+			err := svc.ConfirmAccountDeletion(context.Background(), "token")
+			if tc.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.expected)
+			}
+		})
+	}
+
+	svc := &UserService{Repo: &anonymizeRepo{err: errors.New("boom")}}
+	err := svc.ConfirmAccountDeletion(context.Background(), "token")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, customErrors.Conflict) || errors.Is(err, customErrors.InvalidInput))
+}
@@ -0,0 +1,113 @@
+package users
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	repoUsers "ecom/server/repos/users"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const emailChangeTTL = 24 * time.Hour
+
+// callerID returns the id of the authenticated user stored in ctx.
+func callerID(ctx context.Context) (int64, error) {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return 0, customErrors.Unauthorized
+	}
+	return p.UserID, nil
+}
+
+// Me returns the profile of the caller.
+func (svc *UserService) Me(ctx context.Context) (types.UserProfile, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return types.UserProfile{}, err
+	}
+	u, err := svc.Repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.UserProfile{}, customErrors.NotFound
+		}
+		return types.UserProfile{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	return svc.profile(ctx, u)
+}
+
+func (svc *UserService) profile(ctx context.Context, u types.User) (types.UserProfile, error) {
+	pending, err := svc.Repo.GetActiveUserTokenPayload(ctx, u.ID, repoUsers.TokenEmailChange)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return types.UserProfile{}, fmt.Errorf("failed to get pending email: %w", err)
+	}
+	return types.UserProfile{User: u, PendingEmail: pending}, nil
+}
+
+// UpdateMe updates the caller's profile. Name and phone apply immediately; a new email
+// is only stored once confirmed with the token sent to it.
+func (svc *UserService) UpdateMe(ctx context.Context, req types.UpdateProfileRequest) (types.UserProfile, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return types.UserProfile{}, err
+	}
+
+	u, err := svc.Repo.UpdateProfile(ctx, userID, req.Name, req.Phone)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.UserProfile{}, customErrors.NotFound
+		}
+		return types.UserProfile{}, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	if req.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*req.Email))
+		if email != strings.ToLower(u.Email) {
+			if err := svc.requestEmailChange(ctx, u, email); err != nil {
+				return types.UserProfile{}, err
+			}
+		}
+	}
+	return svc.profile(ctx, u)
+}
+
+func (svc *UserService) requestEmailChange(ctx context.Context, u types.User, email string) error {
+	_, err := svc.Repo.GetByEmail(ctx, email)
+	if err == nil {
+		return fmt.Errorf("%w: email already registered", customErrors.AlreadyExists)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+
+	// Issuing a token invalidates older ones, so only the latest requested address can be confirmed.
+	token, err := svc.issueUserToken(ctx, u.ID, repoUsers.TokenEmailChange, email, emailChangeTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Please confirm the new email address of your account by opening this link:\n\n%s\n\nThe link expires in %s.\n",
+		svc.link("/confirm-email", token), emailChangeTTL)
+	return svc.sendMail(ctx, email, "Confirm your new email address", body)
+}
+
+// ConfirmEmailChange applies the email change the token was issued for. If the address was
+// taken in the meantime, the token isn't used up.
+func (svc *UserService) ConfirmEmailChange(ctx context.Context, token string) error {
+	if err := svc.Repo.ChangeEmail(ctx, auth.HashToken(token)); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return fmt.Errorf("%w: email already registered", customErrors.AlreadyExists)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: invalid or expired token", customErrors.InvalidInput)
+		}
+		return fmt.Errorf("failed to change email: %w", err)
+	}
+	return nil
+}
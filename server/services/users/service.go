@@ -0,0 +1,181 @@
+package users
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/mailer"
+	"ecom/server/repos"
+	repoUsers "ecom/server/repos/users"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgUniqueViolation is the postgres error code for a unique constraint violation.
+const pgUniqueViolation = "23505"
+
+// Config holds the tunables of the user service.
+type Config struct {
+	RefreshTTL       time.Duration // Lifetime of a refresh token, renewed on every rotation.
+	AppURL           string        // Base URL of the frontend, used for links in emails.
+	TOTPIssuer       string        // Name authenticator apps show next to the account.
+	MFARequiredRoles []string      // Roles that may only use the admin API with two-factor authentication.
+}
+
+type UserService struct {
+	Repo      repos.IUserRepo
+	Tokens    *auth.JWTManager
+	Passwords *auth.PasswordHasher
+	Mailer    mailer.Mailer
+	OIDC      map[string]OIDCProvider // Social login providers by name, as used in the routes.
+	Config    Config
+}
+
+func NewService(repo repos.IUserRepo, tokens *auth.JWTManager, passwords *auth.PasswordHasher, mail mailer.Mailer, providers map[string]OIDCProvider, cfg Config) *UserService {
+	return &UserService{Repo: repo, Tokens: tokens, Passwords: passwords, Mailer: mail, OIDC: providers, Config: cfg}
+}
+
+// AuthResult is returned by a successful login or refresh.
+// The refresh token is not serialized: handlers hand it out in an HTTPOnly cookie.
+// If MFAChallenge is set, the login needs a second step and no token was issued.
+type AuthResult struct {
+	AccessToken      string        `json:"access_token"`
+	ExpiresAt        time.Time     `json:"expires_at"`
+	User             types.User    `json:"user"`
+	RefreshToken     string        `json:"-"`
+	RefreshExpiresAt time.Time     `json:"-"`
+	MFAChallenge     *MFAChallenge `json:"-"`
+}
+
+// Client describes where a login or refresh request comes from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// MFAChallenge is returned instead of tokens when the user has to enter a second factor.
+type MFAChallenge struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (svc *UserService) Signup(ctx context.Context, req types.SignupRequest) (types.User, error) {
+	hash, err := svc.Passwords.Hash(req.Password)
+	if err != nil {
+		return types.User{}, err
+	}
+
+	u, err := svc.Repo.Create(ctx, strings.ToLower(strings.TrimSpace(req.Email)), hash)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return types.User{}, customErrors.AlreadyExists
+		}
+		return types.User{}, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	// The account is usable without verification, so a mail failure doesn't fail the signup.
+	if err := svc.sendVerificationEmail(ctx, u); err != nil {
+		log.Printf("email verification for user %d: %v", u.ID, err)
+	}
+	return u, nil
+}
+
+// Login checks the credentials of a user. Repeated failures for one account or from one
+// IP lock further attempts for an exponentially growing time.
+func (svc *UserService) Login(ctx context.Context, req types.LoginRequest, client Client) (AuthResult, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if err := svc.checkLoginLocks(ctx, email, client.IP); err != nil {
+		return AuthResult{}, err
+	}
+
+	u, err := svc.Repo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AuthResult{}, svc.recordLoginFailure(ctx, email, client.IP)
+		}
+		return AuthResult{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	ok, needsRehash := svc.Passwords.Verify(u.Pass, req.Password)
+	if !ok {
+		return AuthResult{}, svc.recordLoginFailure(ctx, email, client.IP)
+	}
+	if needsRehash {
+		// Failing to upgrade the stored hash must not fail the login; it is retried next time.
+		if hash, err := svc.Passwords.Hash(req.Password); err != nil {
+			log.Printf("failed to rehash password of user %d: %v", u.ID, err)
+		} else if err := svc.Repo.UpdatePassword(ctx, u.ID, hash); err != nil {
+			log.Printf("failed to store rehashed password of user %d: %v", u.ID, err)
+		}
+	}
+	if err := svc.Repo.ClearLoginFailures(ctx, repoUsers.AttemptScopeAccount, email); err != nil {
+		return AuthResult{}, fmt.Errorf("failed to clear login failures: %w", err)
+	}
+
+	if u.TOTPEnabled {
+		return svc.mfaChallenge(u)
+	}
+	return svc.startSession(ctx, u, false, client)
+}
+
+// Authenticate verifies an access token and returns the caller it was issued to.
+// Tokens of a session that was ended or expired are rejected, even before they expire,
+// and the use is recorded as the session's last use.
+func (svc *UserService) Authenticate(ctx context.Context, accessToken string) (auth.Principal, error) {
+	claims, err := svc.Tokens.ParseAccessToken(accessToken)
+	if err != nil {
+		return auth.Principal{}, customErrors.Unauthorized
+	}
+	userID, err := claims.UserID()
+	if err != nil || claims.SessionID == "" {
+		return auth.Principal{}, customErrors.Unauthorized
+	}
+	active, err := svc.Repo.TouchSession(ctx, claims.SessionID)
+	if err != nil {
+		return auth.Principal{}, fmt.Errorf("failed to check session: %w", err)
+	}
+	if !active {
+		return auth.Principal{}, customErrors.Unauthorized
+	}
+	return auth.Principal{UserID: userID, Role: claims.Role, SessionID: claims.SessionID, MFA: claims.MFA}, nil
+}
+
+// MigratePasswords tags every legacy stored password, hashing plaintext ones.
+// It returns the number of users updated.
+func (svc *UserService) MigratePasswords(ctx context.Context) (int, error) {
+	var updated int
+	var cursor int64
+	for {
+		us, err := svc.Repo.List(ctx, cursor, 100)
+		if err != nil {
+			return updated, fmt.Errorf("failed to list users: %w", err)
+		}
+		if len(us) == 0 {
+			return updated, nil
+		}
+
+		for _, u := range us {
+			hash, changed, err := svc.Passwords.Upgrade(u.Pass)
+			if err != nil {
+				return updated, fmt.Errorf("failed to upgrade password of user %d: %w", u.ID, err)
+			}
+			if !changed {
+				continue
+			}
+			if err := svc.Repo.UpdatePassword(ctx, u.ID, hash); err != nil {
+				return updated, fmt.Errorf("failed to store password of user %d: %w", u.ID, err)
+			}
+			updated++
+		}
+		cursor = us[len(us)-1].ID
+	}
+}
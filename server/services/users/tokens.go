@@ -0,0 +1,187 @@
+package users
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	repoUsers "ecom/server/repos/users"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxUserAgentLen is the size of the user_agent column.
+const maxUserAgentLen = 255
+
+// startSession issues an access token and the first refresh token of a new token family.
+// mfa records whether the user passed a second factor.
+func (svc *UserService) startSession(ctx context.Context, u types.User, mfa bool, client Client) (AuthResult, error) {
+	familyID, err := auth.NewID()
+	if err != nil {
+		return AuthResult{}, err
+	}
+	refresh, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return AuthResult{}, err
+	}
+	rt, err := svc.Repo.CreateRefreshToken(ctx, repoUsers.RefreshToken{
+		UserID:    u.ID,
+		FamilyID:  familyID,
+		MFA:       mfa,
+		UserAgent: truncate(client.UserAgent, maxUserAgentLen),
+		IP:        client.IP,
+		ExpiresAt: time.Now().Add(svc.Config.RefreshTTL),
+	}, hash)
+	if err != nil {
+		return AuthResult{}, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return svc.authResult(u, refresh, rt)
+}
+
+func (svc *UserService) authResult(u types.User, refresh string, rt repoUsers.RefreshToken) (AuthResult, error) {
+	access, exp, err := svc.Tokens.IssueAccessToken(u.ID, u.Role, rt.FamilyID, rt.MFA)
+	if err != nil {
+		return AuthResult{}, err
+	}
+	return AuthResult{
+		AccessToken:      access,
+		ExpiresAt:        exp,
+		User:             u,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: rt.ExpiresAt,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a rotated refresh token.
+// Presenting a token that was already rotated means it leaked, so the whole family is revoked.
+func (svc *UserService) Refresh(ctx context.Context, refresh string, client Client) (AuthResult, error) {
+	rt, err := svc.Repo.GetRefreshTokenByHash(ctx, auth.HashToken(refresh))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AuthResult{}, customErrors.Unauthorized
+		}
+		return AuthResult{}, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if rt.RevokedAt != nil {
+		if err := svc.Repo.RevokeRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+			return AuthResult{}, fmt.Errorf("failed to revoke token family: %w", err)
+		}
+		return AuthResult{}, customErrors.Unauthorized
+	}
+	if time.Now().After(rt.ExpiresAt) {
+		return AuthResult{}, customErrors.Unauthorized
+	}
+
+	u, err := svc.Repo.Get(ctx, rt.UserID)
+	if err != nil {
+		return AuthResult{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	next, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return AuthResult{}, err
+	}
+	newRT, err := svc.Repo.RotateRefreshToken(ctx, rt.ID, repoUsers.RefreshToken{
+		UserAgent: truncate(client.UserAgent, maxUserAgentLen),
+		IP:        client.IP,
+		ExpiresAt: time.Now().Add(svc.Config.RefreshTTL),
+	}, hash)
+	if err != nil {
+		if errors.Is(err, repoUsers.ErrAlreadyRevoked) {
+			// Lost a race against another use of the same token: treat it as reuse.
+			if err := svc.Repo.RevokeRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+				return AuthResult{}, fmt.Errorf("failed to revoke token family: %w", err)
+			}
+			return AuthResult{}, customErrors.Unauthorized
+		}
+		return AuthResult{}, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	return svc.authResult(u, next, newRT)
+}
+
+// Logout revokes the token family of the given refresh token. Unknown tokens are ignored.
+func (svc *UserService) Logout(ctx context.Context, refresh string) error {
+	rt, err := svc.Repo.GetRefreshTokenByHash(ctx, auth.HashToken(refresh))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if err := svc.Repo.RevokeRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return nil
+}
+
+// ListSessions returns the active sessions of a user. current is the session ID of the
+// caller, if they are looking at their own sessions.
+func (svc *UserService) ListSessions(ctx context.Context, userID int64, current string) ([]types.Session, error) {
+	ss, err := svc.Repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for i := range ss {
+		ss[i].Current = ss[i].ID == current
+	}
+	return ss, nil
+}
+
+// RevokeSession ends one session of a user. Access tokens issued from it are rejected
+// from then on.
+func (svc *UserService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	if err := svc.Repo.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllSessions ends every session of a user.
+func (svc *UserService) RevokeAllSessions(ctx context.Context, userID int64) error {
+	if err := svc.Repo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// MySessions returns the caller's active sessions, flagging the one they are using.
+func (svc *UserService) MySessions(ctx context.Context) ([]types.Session, error) {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, customErrors.Unauthorized
+	}
+	return svc.ListSessions(ctx, p.UserID, p.SessionID)
+}
+
+// RevokeMySession ends one of the caller's sessions.
+func (svc *UserService) RevokeMySession(ctx context.Context, sessionID string) error {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+	return svc.RevokeSession(ctx, userID, sessionID)
+}
+
+// RevokeMySessions logs the caller out everywhere, including the current session.
+func (svc *UserService) RevokeMySessions(ctx context.Context) error {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+	return svc.RevokeAllSessions(ctx, userID)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
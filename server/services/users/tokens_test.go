@@ -0,0 +1,200 @@
+package users
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/repos"
+	repoUsers "ecom/server/repos/users"
+	"ecom/server/types"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenRepo keeps refresh tokens in memory, by hash.
+type tokenRepo struct {
+	repos.IUserRepo
+	tokens  map[string]*repoUsers.RefreshToken
+	touched []string
+}
+
+func newTokenRepo() *tokenRepo {
+	return &tokenRepo{tokens: map[string]*repoUsers.RefreshToken{}}
+}
+
+func (r *tokenRepo) Get(_ context.Context, userID int64) (types.User, error) {
+	return types.User{ID: userID, Role: "user"}, nil
+}
+
+func (r *tokenRepo) CreateRefreshToken(_ context.Context, t repoUsers.RefreshToken, tokenHash string) (repoUsers.RefreshToken, error) {
+	t.ID = int64(len(r.tokens) + 1)
+	t.CreatedAt = time.Now()
+	r.tokens[tokenHash] = &t
+	return t, nil
+}
+
+func (r *tokenRepo) GetRefreshTokenByHash(_ context.Context, tokenHash string) (repoUsers.RefreshToken, error) {
+	t, ok := r.tokens[tokenHash]
+	if !ok {
+		return repoUsers.RefreshToken{}, pgx.ErrNoRows
+	}
+	return *t, nil
+}
+
+func (r *tokenRepo) RotateRefreshToken(ctx context.Context, oldID int64, next repoUsers.RefreshToken, newHash string) (repoUsers.RefreshToken, error) {
+	for _, t := range r.tokens {
+		if t.ID != oldID {
+			continue
+		}
+		if t.RevokedAt != nil {
+			return repoUsers.RefreshToken{}, repoUsers.ErrAlreadyRevoked
+		}
+		now := time.Now()
+		t.RevokedAt = &now
+		next.UserID, next.FamilyID, next.MFA = t.UserID, t.FamilyID, t.MFA
+		return r.CreateRefreshToken(ctx, next, newHash)
+	}
+	return repoUsers.RefreshToken{}, pgx.ErrNoRows
+}
+
+func (r *tokenRepo) RevokeRefreshTokenFamily(_ context.Context, familyID string) error {
+	now := time.Now()
+	for _, t := range r.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *tokenRepo) RevokeSession(ctx context.Context, userID int64, familyID string) error {
+	if active, _ := r.active(familyID); !active || r.owner(familyID) != userID {
+		return pgx.ErrNoRows
+	}
+	return r.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func (r *tokenRepo) TouchSession(_ context.Context, familyID string) (bool, error) {
+	r.touched = append(r.touched, familyID)
+	return r.active(familyID)
+}
+
+func (r *tokenRepo) active(familyID string) (bool, error) {
+	for _, t := range r.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil && t.ExpiresAt.After(time.Now()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *tokenRepo) owner(familyID string) int64 {
+	for _, t := range r.tokens {
+		if t.FamilyID == familyID {
+			return t.UserID
+		}
+	}
+	return 0
+}
+
+func newTokenService(repo *tokenRepo) *UserService {
+	return &UserService{
+		Repo:   repo,
+		Tokens: auth.NewJWTManager("secret", time.Minute),
+		Config: Config{RefreshTTL: time.Hour},
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	svc := newTokenService(newTokenRepo())
+	ctx := context.Background()
+	u := types.User{ID: 1, Role: "user"}
+
+	login, err := svc.startSession(ctx, u, false, Client{})
+	require.NoError(t, err)
+
+	first, err := svc.Refresh(ctx, login.RefreshToken, Client{})
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, first.RefreshToken, "the refresh token is rotated")
+
+	second, err := svc.Refresh(ctx, first.RefreshToken, Client{})
+	require.NoError(t, err)
+	_, err = svc.Authenticate(ctx, second.AccessToken)
+	assert.NoError(t, err)
+
+	t.Run("Reusing a rotated token revokes the whole family", func(t *testing.T) {
+		_, err := svc.Refresh(ctx, first.RefreshToken, Client{})
+		assert.ErrorIs(t, err, customErrors.Unauthorized)
+
+		_, err = svc.Refresh(ctx, second.RefreshToken, Client{})
+		assert.ErrorIs(t, err, customErrors.Unauthorized, "the latest token of the family was revoked too")
+		_, err = svc.Authenticate(ctx, second.AccessToken)
+		assert.ErrorIs(t, err, customErrors.Unauthorized)
+	})
+
+	t.Run("Other sessions are kept", func(t *testing.T) {
+		other, err := svc.startSession(ctx, u, false, Client{})
+		require.NoError(t, err)
+		_, err = svc.Refresh(ctx, login.RefreshToken, Client{})
+		assert.ErrorIs(t, err, customErrors.Unauthorized)
+
+		_, err = svc.Refresh(ctx, other.RefreshToken, Client{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unknown tokens are rejected", func(t *testing.T) {
+		_, err := svc.Refresh(ctx, "not-a-token", Client{})
+		assert.ErrorIs(t, err, customErrors.Unauthorized)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	svc := newTokenService(newTokenRepo())
+	ctx := context.Background()
+
+	login, err := svc.startSession(ctx, types.User{ID: 1, Role: "user"}, false, Client{})
+	require.NoError(t, err)
+	next, err := svc.Refresh(ctx, login.RefreshToken, Client{})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Logout(ctx, next.RefreshToken))
+
+	_, err = svc.Refresh(ctx, next.RefreshToken, Client{})
+	assert.ErrorIs(t, err, customErrors.Unauthorized)
+	_, err = svc.Authenticate(ctx, next.AccessToken)
+	assert.ErrorIs(t, err, customErrors.Unauthorized)
+
+	assert.NoError(t, svc.Logout(ctx, "not-a-token"), "unknown tokens are ignored")
+}
+
+func TestAuthenticateRevokedSession(t *testing.T) {
+	repo := newTokenRepo()
+	svc := newTokenService(repo)
+	ctx := context.Background()
+	u := types.User{ID: 1, Role: "user"}
+
+	laptop, err := svc.startSession(ctx, u, false, Client{})
+	require.NoError(t, err)
+	phone, err := svc.startSession(ctx, u, false, Client{})
+	require.NoError(t, err)
+
+	p, err := svc.Authenticate(ctx, laptop.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{p.SessionID}, repo.touched, "the use is recorded for the session list")
+
+	require.NoError(t, svc.RevokeSession(ctx, 1, p.SessionID))
+
+	_, err = svc.Authenticate(ctx, laptop.AccessToken)
+	assert.ErrorIs(t, err, customErrors.Unauthorized, "the token is rejected right after its session is revoked")
+	_, err = svc.Authenticate(ctx, phone.AccessToken)
+	assert.NoError(t, err, "other sessions keep working")
+
+	sessionless, _, err := svc.Tokens.IssueAccessToken(1, "user", "", false)
+	require.NoError(t, err)
+	_, err = svc.Authenticate(ctx, sessionless)
+	assert.ErrorIs(t, err, customErrors.Unauthorized)
+}
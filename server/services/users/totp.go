@@ -0,0 +1,225 @@
+package users
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	repoUsers "ecom/server/repos/users"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const recoveryCodeCount = 10
+
+// MFARequired reports whether users with the role must use two-factor authentication.
+func (svc *UserService) MFARequired(role string) bool {
+	return slices.Contains(svc.Config.MFARequiredRoles, role)
+}
+
+func (svc *UserService) mfaChallenge(u types.User) (AuthResult, error) {
+	token, exp, err := svc.Tokens.IssueMFAToken(u.ID)
+	if err != nil {
+		return AuthResult{}, err
+	}
+	return AuthResult{User: u, MFAChallenge: &MFAChallenge{MFARequired: true, MFAToken: token, ExpiresAt: exp}}, nil
+}
+
+// LoginMFA is the second step of a login: it checks a TOTP or recovery code for the user
+// the MFA token was issued to and starts the session. Wrong codes count as failed logins.
+func (svc *UserService) LoginMFA(ctx context.Context, req types.MFALoginRequest, client Client) (AuthResult, error) {
+	claims, err := svc.Tokens.ParseMFAToken(req.MFAToken)
+	if err != nil {
+		return AuthResult{}, fmt.Errorf("%w: invalid or expired mfa token", customErrors.Unauthorized)
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return AuthResult{}, customErrors.Unauthorized
+	}
+	u, err := svc.Repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AuthResult{}, customErrors.Unauthorized
+		}
+		return AuthResult{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	email := strings.ToLower(u.Email)
+	if err := svc.checkLoginLocks(ctx, email, client.IP); err != nil {
+		return AuthResult{}, err
+	}
+
+	ok, err := svc.checkSecondFactor(ctx, u.ID, req.Code, req.RecoveryCode)
+	if err != nil {
+		return AuthResult{}, err
+	}
+	if !ok {
+		err := svc.recordLoginFailure(ctx, email, client.IP)
+		if errors.Is(err, customErrors.InvalidCredentials) {
+			return AuthResult{}, fmt.Errorf("%w: invalid two-factor code", customErrors.Unauthorized)
+		}
+		return AuthResult{}, err
+	}
+	if err := svc.Repo.ClearLoginFailures(ctx, repoUsers.AttemptScopeAccount, email); err != nil {
+		return AuthResult{}, fmt.Errorf("failed to clear login failures: %w", err)
+	}
+	return svc.startSession(ctx, u, true, client)
+}
+
+// checkSecondFactor verifies a TOTP code, or else a recovery code, consuming it.
+func (svc *UserService) checkSecondFactor(ctx context.Context, userID int64, code, recoveryCode string) (bool, error) {
+	st, err := svc.Repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get totp state: %w", err)
+	}
+	if !st.Enabled {
+		return false, nil
+	}
+
+	if code != "" {
+		step, ok := auth.VerifyTOTP(st.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		fresh, err := svc.Repo.UseTOTPStep(ctx, userID, step)
+		if err != nil {
+			return false, fmt.Errorf("failed to record totp step: %w", err)
+		}
+		return fresh, nil
+	}
+
+	used, err := svc.Repo.UseRecoveryCode(ctx, userID, auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode)))
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return used, nil
+}
+
+// EnrollTOTP starts two-factor enrollment for the caller with a fresh secret.
+// It only takes effect once confirmed with a code.
+func (svc *UserService) EnrollTOTP(ctx context.Context) (types.TOTPEnrollment, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return types.TOTPEnrollment{}, err
+	}
+	u, err := svc.Repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return types.TOTPEnrollment{}, customErrors.NotFound
+		}
+		return types.TOTPEnrollment{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	if u.TOTPEnabled {
+		return types.TOTPEnrollment{}, fmt.Errorf("%w: two-factor authentication is already enabled", customErrors.AlreadyExists)
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return types.TOTPEnrollment{}, err
+	}
+	if err := svc.Repo.SetTOTPSecret(ctx, userID, secret); err != nil {
+		return types.TOTPEnrollment{}, fmt.Errorf("failed to store totp secret: %w", err)
+	}
+	return types.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(svc.Config.TOTPIssuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their app produces
+// valid codes, and returns the recovery codes. They are never shown again.
+func (svc *UserService) ConfirmTOTP(ctx context.Context, code string) (types.RecoveryCodes, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return types.RecoveryCodes{}, err
+	}
+	st, err := svc.Repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return types.RecoveryCodes{}, fmt.Errorf("failed to get totp state: %w", err)
+	}
+	if st.Enabled {
+		return types.RecoveryCodes{}, fmt.Errorf("%w: two-factor authentication is already enabled", customErrors.AlreadyExists)
+	}
+	if st.Secret == "" {
+		return types.RecoveryCodes{}, fmt.Errorf("%w: start the enrollment first", customErrors.InvalidInput)
+	}
+
+	step, ok := auth.VerifyTOTP(st.Secret, code, time.Now())
+	if !ok {
+		return types.RecoveryCodes{}, fmt.Errorf("%w: invalid code", customErrors.InvalidInput)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return types.RecoveryCodes{}, err
+	}
+	if err := svc.Repo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		return types.RecoveryCodes{}, fmt.Errorf("failed to enable totp: %w", err)
+	}
+	return types.RecoveryCodes{Codes: codes}, nil
+}
+
+// DisableTOTP turns two-factor authentication off for the caller, who must prove
+// possession of the second factor. Roles that require it can't turn it off.
+func (svc *UserService) DisableTOTP(ctx context.Context, code string) error {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return customErrors.Unauthorized
+	}
+	if svc.MFARequired(p.Role) {
+		return fmt.Errorf("%w: two-factor authentication is mandatory for your role", customErrors.Forbidden)
+	}
+
+	ok, err := svc.checkSecondFactor(ctx, p.UserID, code, "")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: invalid code", customErrors.InvalidInput)
+	}
+	if err := svc.Repo.DisableTOTP(ctx, p.UserID); err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes with new ones.
+func (svc *UserService) RegenerateRecoveryCodes(ctx context.Context, code string) (types.RecoveryCodes, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return types.RecoveryCodes{}, err
+	}
+	ok, err := svc.checkSecondFactor(ctx, userID, code, "")
+	if err != nil {
+		return types.RecoveryCodes{}, err
+	}
+	if !ok {
+		return types.RecoveryCodes{}, fmt.Errorf("%w: invalid code", customErrors.InvalidInput)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return types.RecoveryCodes{}, err
+	}
+	if err := svc.Repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return types.RecoveryCodes{}, fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	return types.RecoveryCodes{Codes: codes}, nil
+}
+
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for range recoveryCodeCount {
+		code, err := auth.NewRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,148 @@
+package users
+
+import (
+	"context"
+	"ecom/server/auth"
+	"ecom/server/customErrors"
+	"ecom/server/mailer"
+	repoUsers "ecom/server/repos/users"
+	"ecom/server/types"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	emailVerifyTTL   = 48 * time.Hour
+	passwordResetTTL = time.Hour
+)
+
+// issueUserToken invalidates the user's previous tokens for the purpose and stores a new one,
+// returning it in the clear so it can be mailed.
+func (svc *UserService) issueUserToken(ctx context.Context, userID int64, purpose, payload string, ttl time.Duration) (string, error) {
+	if err := svc.Repo.InvalidateUserTokens(ctx, userID, purpose); err != nil {
+		return "", fmt.Errorf("failed to invalidate %s tokens: %w", purpose, err)
+	}
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := svc.Repo.CreateUserToken(ctx, userID, purpose, hash, payload, time.Now().Add(ttl)); err != nil {
+		return "", fmt.Errorf("failed to store %s token: %w", purpose, err)
+	}
+	return token, nil
+}
+
+// link returns an absolute frontend URL carrying a token.
+func (svc *UserService) link(path, token string) string {
+	return strings.TrimRight(svc.Config.AppURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func (svc *UserService) sendMail(ctx context.Context, to, subject, body string) error {
+	if err := svc.Mailer.Send(ctx, mailer.Message{To: to, Subject: subject, Body: body}); err != nil {
+		return fmt.Errorf("failed to send %q email: %w", subject, err)
+	}
+	return nil
+}
+
+// sendVerificationEmail mails a link confirming that the user owns their address.
+func (svc *UserService) sendVerificationEmail(ctx context.Context, u types.User) error {
+	token, err := svc.issueUserToken(ctx, u.ID, repoUsers.TokenEmailVerify, "", emailVerifyTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Welcome! Please confirm your email address by opening this link:\n\n%s\n\nThe link expires in %s.\n",
+		svc.link("/verify-email", token), emailVerifyTTL)
+	return svc.sendMail(ctx, u.Email, "Confirm your email address", body)
+}
+
+// VerifyEmail marks the email of the user the token was issued to as verified.
+func (svc *UserService) VerifyEmail(ctx context.Context, token string) error {
+	userID, _, err := svc.Repo.ConsumeUserToken(ctx, repoUsers.TokenEmailVerify, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: invalid or expired token", customErrors.InvalidInput)
+		}
+		return fmt.Errorf("failed to consume verification token: %w", err)
+	}
+	if err := svc.Repo.MarkEmailVerified(ctx, userID); err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	return nil
+}
+
+// ResendVerification mails a new verification link to the caller, unless already verified.
+func (svc *UserService) ResendVerification(ctx context.Context) error {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+	u, err := svc.Repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return customErrors.NotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if u.EmailVerified {
+		return nil
+	}
+	return svc.sendVerificationEmail(ctx, u)
+}
+
+// ForgotPassword mails a password reset link if the email belongs to an account.
+// It reports success either way, so it can't be used to find out who has an account.
+func (svc *UserService) ForgotPassword(ctx context.Context, email string) error {
+	u, err := svc.Repo.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, err := svc.issueUserToken(ctx, u.ID, repoUsers.TokenPasswordReset, "", passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Someone asked to reset the password of your account. If it was you, open this link:\n\n%s\n\n"+
+		"The link expires in %s. If you didn't ask for it, you can ignore this email.\n",
+		svc.link("/reset-password", token), passwordResetTTL)
+	if err := svc.sendMail(ctx, u.Email, "Reset your password", body); err != nil {
+		log.Printf("password reset for user %d: %v", u.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password for the user the token was issued to and ends all
+// of their sessions.
+func (svc *UserService) ResetPassword(ctx context.Context, req types.ResetPasswordRequest) error {
+	userID, _, err := svc.Repo.ConsumeUserToken(ctx, repoUsers.TokenPasswordReset, auth.HashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: invalid or expired token", customErrors.InvalidInput)
+		}
+		return fmt.Errorf("failed to consume reset token: %w", err)
+	}
+
+	hash, err := svc.Passwords.Hash(req.Password)
+	if err != nil {
+		return err
+	}
+	if err := svc.Repo.UpdatePassword(ctx, userID, hash); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if err := svc.Repo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	// Receiving the reset link proves ownership of the address too.
+	if err := svc.Repo.MarkEmailVerified(ctx, userID); err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	return nil
+}
@@ -6,6 +6,135 @@ import (
 )
 
 type User struct {
+	ID             int64      `json:"id"`
+	Email          string     `json:"email"`
+	Pass           string     `json:"-"` // Password hash, never serialized
+	Role           string     `json:"role"`
+	Name           string     `json:"name"`
+	Phone          string     `json:"phone"`
+	EmailVerified  bool       `json:"email_verified"`
+	TOTPEnabled    bool       `json:"two_factor_enabled"`
+	ServiceAccount bool       `json:"service_account,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"` // Set once the account was deleted and anonymized
+}
+
+// UserProfile is the caller's own view of their account.
+type UserProfile struct {
+	User
+	PendingEmail string `json:"pending_email,omitempty"` // New email awaiting confirmation
+}
+
+// Address is an entry of a user's address book.
+type Address struct {
+	ID        int64  `json:"id"`
+	Line1     string `json:"line1"`
+	City      string `json:"city"`
+	State     string `json:"state"`
+	ZipCode   string `json:"zip_code"`
+	Country   string `json:"country"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// Session is a login of a user on one device, kept alive by refresh token rotation.
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	MFA        bool      `json:"two_factor"`
+	Current    bool      `json:"current"` // The session of the caller.
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Order is a past or ongoing purchase. ShippingAddressID is nil once the address was deleted.
+type Order struct {
+	ID                int64       `json:"id"`
+	Status            string      `json:"status"`
+	TotalAmount       float64     `json:"total_amount"`
+	PaymentMethod     string      `json:"payment_method"`
+	ShippingAddressID *int64      `json:"shipping_address_id"`
+	Items             []OrderItem `json:"items"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+type OrderItem struct {
+	ProductID   int64   `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	Quantity    int     `json:"quantity"`
+	Price       float64 `json:"price"` // Unit price paid
+}
+
+// Rating is a user's score and review of a product.
+type Rating struct {
+	ProductID   int64     `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Score       int       `json:"score"`
+	Review      string    `json:"review"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type WishlistItem struct {
+	ProductID   int64     `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+type CartItem struct {
+	ProductID   int64  `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Quantity    int    `json:"quantity"`
+}
+
+// LinkedAccount is an account at an OpenID Connect provider the user logs in with.
+type LinkedAccount struct {
+	Provider string    `json:"provider"`
+	Email    string    `json:"email"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// AccountExport is everything stored about a user, as handed out by the data export.
+type AccountExport struct {
+	ExportedAt     time.Time       `json:"exported_at"`
+	Profile        User            `json:"profile"`
+	Addresses      []Address       `json:"addresses"`
+	Orders         []Order         `json:"orders"`
+	Ratings        []Rating        `json:"ratings"`
+	Wishlist       []WishlistItem  `json:"wishlist"`
+	Cart           []CartItem      `json:"cart"`
+	Sessions       []Session       `json:"sessions"`
+	LinkedAccounts []LinkedAccount `json:"linked_accounts"`
+}
+
+// Category is a node of the category tree. ProductCount includes the products of its subcategories.
+type Category struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	ParentID     *int64     `json:"parent_id"`
+	ProductCount int        `json:"product_count"`
+	Children     []Category `json:"children,omitempty"`
+}
+
+// CategoryRef names a category, as in the breadcrumb path of a product.
+type CategoryRef struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// CategoryRequest is the JSON body to create or replace a category. A nil ParentID makes a top level category.
+type CategoryRequest struct {
+	Name     string `json:"name" validate:"required,max=60"`
+	ParentID *int64 `json:"parent_id" validate:"omitnil,gt=0"`
+}
+
+// CategoryDeletion reports what a category deletion changes: its products are left without
+// a category and its subcategories move up to its parent. Deleted is false for a dry run.
+type CategoryDeletion struct {
+	Deleted            bool `json:"deleted"`
+	OrphanedProducts   int  `json:"orphaned_products"`
+	MovedSubcategories int  `json:"moved_subcategories"`
 }
 
 type MiniProduct struct {
@@ -19,6 +148,7 @@ type MiniProduct struct {
 	} `json:"category_data"`
 	Image     json.RawMessage `json:"image"` // A single image object {url:string, alt_text:string}
 	CreatedAt time.Time       `json:"created_at"`
+	Relevance float32         `json:"relevance,omitempty"` // How well the product matches the search, the sort value of sortBy=relevance
 }
 
 type Product struct {
@@ -28,22 +158,266 @@ type Product struct {
 	Price        float64 `json:"price"`
 	AvgRating    float64 `json:"average_rating"` // Average rating out of 5
 	CategoryData struct {
-		ID   int64  `json:"id"`
-		Name string `json:"name"`
+		ID   int64         `json:"id"`
+		Name string        `json:"name"`
+		Path []CategoryRef `json:"path"` // Breadcrumbs from the top level category down to this one
 	} `json:"category_data"`
 	Images    json.RawMessage `json:"images"` // JSON array of image objects {url:string, alt_text:string}
 	CreatedAt time.Time       `json:"created_at"`
 }
 
+// SuggestRequest defines query params for the search-as-you-type suggestions.
+type SuggestRequest struct {
+	Query string `validate:"required,min=2,max=100"`
+	Limit int    `validate:"omitempty,gte=1,lte=20"`
+}
+
+// Suggestions are what the search box offers while the user types.
+type Suggestions struct {
+	Products   []ProductSuggestion `json:"products"`
+	Categories []CategoryRef       `json:"categories"`
+}
+
+type ProductSuggestion struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Facets count the products of a list by category, price range and minimum rating. Each facet
+// applies all filters of the list but its own, so a choice can be swapped for another.
+type Facets struct {
+	Categories []CategoryFacet `json:"categories"`
+	Prices     []PriceFacet    `json:"prices"`
+	Ratings    []RatingFacet   `json:"ratings"`
+}
+
+// CategoryFacet counts the matching products of a category, including those of its subcategories.
+type CategoryFacet struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// PriceFacet counts the products priced from Min up to, but excluding, Max. The last range has no Max.
+type PriceFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int      `json:"count"`
+}
+
+// RatingFacet counts the products rated MinScore or more on average.
+type RatingFacet struct {
+	MinScore int `json:"min_score"`
+	Count    int `json:"count"`
+}
+
+// SearchClickRequest is the JSON body recording that a product was opened from a search's results.
+type SearchClickRequest struct {
+	SearchToken string `json:"search_token" validate:"required,max=100"`
+}
+
+// SearchAnalyticsRequest defines query params for the search reports.
+type SearchAnalyticsRequest struct {
+	Days  int `validate:"omitempty,gte=1,lte=365"`
+	Limit int `validate:"omitempty,gte=1,lte=100"`
+}
+
+// SearchAnalytics reports on what customers searched for since a time.
+type SearchAnalytics struct {
+	Since              time.Time          `json:"since"`
+	TopQueries         []SearchQueryStats `json:"top_queries"`          // Most searched first
+	ZeroResultQueries  []SearchQueryStats `json:"zero_result_queries"`  // Only the searches that found nothing, most searched first
+	LowestClickThrough []SearchQueryStats `json:"lowest_click_through"` // Queries searched often enough that found something, least clicked first
+}
+
+// SearchQueryStats sums up the searches for one query. ClickedSearches counts those after which a
+// product was opened.
+type SearchQueryStats struct {
+	Query            string    `json:"query"`
+	Searches         int       `json:"searches"`
+	ClickedSearches  int       `json:"clicked_searches"`
+	ClickThroughRate float64   `json:"click_through_rate"`
+	LastSearchedAt   time.Time `json:"last_searched_at"`
+}
+
+// SynonymGroup is a set of search terms that find each other's products, e.g. tv and television.
+type SynonymGroup struct {
+	ID        int64     `json:"id"`
+	Terms     []string  `json:"terms"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SynonymGroupRequest is the JSON body to create or replace a group of synonyms.
+type SynonymGroupRequest struct {
+	Terms []string `json:"terms" validate:"required,min=2,max=20,dive,required,max=60"`
+}
+
+// ProductRequest is the JSON body to create or replace a product. Images replace the current ones.
+type ProductRequest struct {
+	Name        string         `json:"name" validate:"required,max=60"`
+	Description string         `json:"description" validate:"max=5000"`
+	Price       float64        `json:"price" validate:"required,gt=0"`
+	CategoryID  *int64         `json:"category_id" validate:"omitnil,gt=0"`
+	Images      []ProductImage `json:"images" validate:"max=20,dive"`
+}
+
+type ProductImage struct {
+	URL     string `json:"url" validate:"required,url,max=500"`
+	AltText string `json:"alt_text" validate:"max=200"`
+}
+
 // GetProductsRequest defines query params for the product list endpoint.
 // Pointers are used for optional fields.
 type GetProductsRequest struct {
-	SortBy       string   `validate:"omitempty,oneof=price created_at"`
+	SortBy       string   `validate:"omitempty,oneof=price created_at relevance"` // relevance needs SearchString
 	Order        string   `validate:"omitempty,oneof=asc desc"`
 	PriceMin     *float64 `validate:"omitempty,gte=0"`
 	PriceMax     *float64 `validate:"omitempty,gte=0,gtfield=PriceMin"`
 	SearchString *string  `validate:"omitempty,min=1,max=100"`
 	MinScore     *int     `validate:"omitempty,gte=1,lte=5"`
+	CategoryIDs  []int64  `validate:"omitempty,max=20,dive,gt=0"`
 	PageNum      int      `validate:"omitempty,gte=1,lte=100"`
-	Cursor       []string `validate:"omitempty,len=2"`
+	Cursor       []string `validate:"omitempty,min=2,max=3"` // The 3rd value is the corrected search of the first page.
+	Facets       bool
+}
+
+// SignupRequest is the JSON body of the signup endpoint.
+type SignupRequest struct {
+	Email    string `json:"email" validate:"required,email,max=60"`
+	Password string `json:"password" validate:"required,min=8,max=72"` // bcrypt ignores anything past 72 bytes
+}
+
+// LoginRequest is the JSON body of the login endpoint.
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email,max=60"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+type Role struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type Permission struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateRoleRequest is the JSON body of the admin create role endpoint.
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=30,lowercase"`
+	Description string   `json:"description" validate:"max=200"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+// SetRolePermissionsRequest replaces the permissions granted to a role.
+type SetRolePermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"required,dive,required"`
+}
+
+// AssignRoleRequest is the JSON body of the admin assign role endpoint.
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required,max=30"`
+}
+
+// APIKey describes a key without its secret, which is only shown once on creation.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKey is returned once when a key is created. Key is the full secret.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyRequest is the JSON body of the admin create API key endpoint.
+// A nil ExpiresAt makes a key that doesn't expire.
+type CreateAPIKeyRequest struct {
+	UserID    int64      `json:"user_id" validate:"required,gt=0"`
+	Name      string     `json:"name" validate:"required,max=60"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateServiceAccountRequest is the JSON body of the admin create service account endpoint.
+type CreateServiceAccountRequest struct {
+	Name string `json:"name" validate:"required,max=40"`
+	Role string `json:"role" validate:"required,max=30"`
+}
+
+// UpdateProfileRequest is the JSON body of PUT /auth/me. Nil fields are left unchanged,
+// empty name or phone clear the field. A new email only applies once confirmed.
+type UpdateProfileRequest struct {
+	Name  *string `json:"name" validate:"omitnil,max=100"`
+	Phone *string `json:"phone" validate:"omitnil,max=30,e164|len=0"`
+	Email *string `json:"email" validate:"omitnil,email,max=60"`
+}
+
+// TokenRequest is the JSON body of endpoints consuming an emailed single-use token.
+type TokenRequest struct {
+	Token string `json:"token" validate:"required,max=100"`
+}
+
+// AddressRequest is the JSON body to create or replace an address.
+type AddressRequest struct {
+	Line1     string `json:"line1" validate:"required,max=200"`
+	City      string `json:"city" validate:"required,max=60"`
+	State     string `json:"state" validate:"max=60"`
+	ZipCode   string `json:"zip_code" validate:"max=20"`
+	Country   string `json:"country" validate:"required,max=60"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// MagicLinkRequest is the JSON body of POST /auth/magic-link.
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email,max=60"`
+}
+
+// ForgotPasswordRequest is the JSON body of POST /auth/password/forgot.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email,max=60"`
+}
+
+// ResetPasswordRequest is the JSON body of POST /auth/password/reset.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// TOTPCodeRequest carries a code from the user's authenticator app.
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+// MFALoginRequest is the second step of a login for users with two-factor authentication.
+// Either a code from the authenticator app or an unused recovery code is required.
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code,omitempty,max=20"`
+}
+
+// TOTPEnrollment is returned when starting two-factor enrollment.
+// ProvisioningURI is meant to be rendered as a QR code.
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodes are shown to the user once, when two-factor authentication is enabled.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
 }